@@ -1,139 +1,140 @@
 package main
 
 import (
-	"bufio"
-	"cloud.google.com/go/storage"
-	"context"
+	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"github.com/mollie/tf-provider-registry-api-generator/versions"
-	"io/ioutil"
-	"log"
+	log "github.com/sirupsen/logrus"
 	"path"
 	"reflect"
-	"strings"
 )
 
-func assertDiscoveryDocument(bucket *storage.BucketHandle) {
+func assertDiscoveryDocument(bucket *Bucket, registry *RegistryConfig) error {
 	content := make(map[string]string)
 	expect := map[string]string{
-		"providers.v1": "/v1/providers/",
+		"providers.v1": "/" + registry.ProvidersPath() + "/",
 	}
 
 	p := path.Join(".well-known", "terraform.json")
 	err := readJson(bucket, p, &content)
 	if err != nil {
-		log.Fatalf("could not read content of %s, %s", p, err)
+		return fmt.Errorf("could not read content of %s, %s", p, err)
 	}
 
+	existed := len(content) > 0
 	if !reflect.DeepEqual(expect, content) {
-		log.Printf("INFO: writing content to %s", p)
-		writeJson(bucket, p, expect)
-	} else {
-		log.Printf("INFO: discovery document is up-to-date\n")
+		log.WithField("path", p).Info("writing discovery document")
+		if err = writeJson(bucket, p, expect, versions.DiscoveryDocument, registry.CacheControl); err != nil {
+			return err
+		}
+		report.Document(p, existed, true)
+		return nil
 	}
+	log.WithField("path", p).Info("discovery document is up-to-date")
+	report.Document(p, existed, false)
+	return nil
 }
 
-func readJson(bucket *storage.BucketHandle, filename string, object interface{}) error {
-	r, err := bucket.Object(filename).NewReader(context.Background())
+func readJson(bucket *Bucket, filename string, object interface{}) error {
+	body, err := bucket.Read(filename)
 	if err != nil {
-		if err.Error() == "storage: object doesn't exist" {
+		if errors.Is(err, errObjectNotExist) {
 			return nil
 		}
-		return fmt.Errorf("ERROR: failed to read file %s, %s", filename, err)
-	}
-	defer r.Close()
-	body, err := ioutil.ReadAll(r)
-	if err != nil {
-		return fmt.Errorf("ERROR: failed to read content from %s, %s", filename, err)
+		return fmt.Errorf("failed to read file %s, %s", filename, err)
 	}
 	err = json.Unmarshal(body, &object)
 	if err != nil {
-		return fmt.Errorf("ERROR: failed to unmarshal %s, %s", filename, err)
+		return fmt.Errorf("failed to unmarshal %s, %s", filename, err)
 	}
 
 	return nil
 }
 
-func readShasums(bucket *storage.BucketHandle, filename string, shasums map[string]string) error {
-	r, err := bucket.Object(filename).NewReader(context.Background())
+// readShasums adds the shasums of the SHA256SUMS file to shasums, keyed by the path of the
+// archive. A malformed file is returned as a validationError.
+func readShasums(bucket *Bucket, filename string, shasums map[string]string) error {
+	content, err := bucket.Read(filename)
 	if err != nil {
-		if err.Error() == "storage: object doesn't exist" {
+		if errors.Is(err, errObjectNotExist) {
 			return nil
 		}
-		return fmt.Errorf("ERROR: failed to read file %s, %s", filename, err)
+		return fmt.Errorf("failed to read file %s, %s", filename, err)
 	}
-	defer r.Close()
 
-	scanner := bufio.NewScanner(r)
-	for scanner.Scan() {
-		fields := strings.Fields(scanner.Text())
-		if len(fields) != 2 {
-			log.Fatalf("ERROR: expected %s to contain 2 fields on each line, found %d", filename, len(fields))
-		}
-		shasums[fields[1]] = fields[0]
+	if err = versions.ParseShasumsOf(bytes.NewReader(content), path.Dir(filename), shasums); err != nil {
+		return validationError{fmt.Errorf("failed to parse %s, %s", filename, err)}
 	}
 	return nil
 }
 
-func writeJson(bucket *storage.BucketHandle, filename string, content interface{}) {
-	log.Printf("INFO: writing %s", filename)
-
-	w := bucket.Object(filename).NewWriter(context.Background())
-	w.ContentType = "application/json"
-	w.CacheControl = "no-cache, max-age=60"
-
-	encoder := json.NewEncoder(w)
-	encoder.SetIndent("", "  ")
-	err := encoder.Encode(content)
+func readObject(bucket *Bucket, filename string) ([]byte, error) {
+	content, err := bucket.Read(filename)
 	if err != nil {
-		log.Fatalf("INFO: failed to write %s, %s", filename, err)
-	}
-	if err = w.Close(); err != nil {
-		log.Fatalf("INFO: failed to close %s, %s", filename, err)
+		return nil, fmt.Errorf("failed to read file %s, %s", filename, err)
 	}
+	return content, nil
 }
 
-func writeProviderVersions(bucket *storage.BucketHandle, directory string, newVersions *versions.ProviderVersions) {
-	var existing versions.ProviderVersions
-	if err := readJson(bucket, path.Join(directory, "versions"), &existing); err != nil {
-		log.Fatalf("ERROR: failed to read the %s/versions, %s", directory, err)
-	}
-	if reflect.DeepEqual(&existing, newVersions) {
-		log.Printf("INFO: %s/versions already up-to-date", directory)
-		return
+// readSignature adds the signature file to signatures, keyed by its path.
+func readSignature(bucket *Bucket, filename string, signatures map[string][]byte) error {
+	signature, err := readObject(bucket, filename)
+	if err != nil {
+		return err
 	}
-	existing.Merge(*newVersions)
-	writeJson(bucket, path.Join(directory, "versions"), existing)
+	signatures[filename] = signature
+	return nil
 }
 
-func writeProviderVersion(bucket *storage.BucketHandle, directory string, version *versions.BinaryMetaData) {
-	filename := path.Join(directory, version.Version, "download", version.Os, version.Arch)
-	existing := versions.BinaryMetaData{}
-
-	if err := readJson(bucket, filename, &existing); err != nil {
-		log.Fatalf("ERROR: failed to read %s, %s", filename, err)
-	}
+func writeObject(bucket *Bucket, filename string, content []byte, contentType string) error {
+	log.WithField("path", filename).Info("writing object")
 
-	if existing.Equals(version) {
-		log.Printf("INFO: %s is up-to-date", filename)
-		return
+	if err := bucket.Write(filename, content, contentType, ""); err != nil {
+		return fmt.Errorf("failed to write %s, %s", filename, err)
 	}
-	writeJson(bucket, filename, version)
+	return nil
 }
 
-func WriteAPIDocuments(bucket *storage.BucketHandle, namespace string, binaries versions.BinaryMetaDataList) {
-	assertDiscoveryDocument(bucket)
-
-	providerDirectory := path.Join("v1", "providers", namespace)
-	providers := binaries.ExtractVersions()
+// writeJson writes the content as a document of the kind, after validating it against the
+// JSON schema of the kind. An invalid document is returned as a validationError.
+func writeJson(bucket *Bucket, filename string, content interface{}, kind versions.DocumentKind, cacheControl string) error {
+	log.WithField("path", filename).Info("writing document")
 
-	for _, binary := range binaries {
-		writeProviderVersion(bucket, path.Join(providerDirectory, binary.TypeName), &binary)
+	document, err := marshalDocument(content)
+	if err != nil {
+		return fmt.Errorf("failed to marshal %s, %s", filename, err)
+	}
+	if err = versions.ValidateDocument(kind, document); err != nil {
+		return validationError{fmt.Errorf("%s, %s", filename, err)}
+	}
+	if err := bucket.Write(filename, document, "application/json", cacheControl); err != nil {
+		return fmt.Errorf("failed to write %s, %s", filename, err)
 	}
+	return nil
+}
 
-	for name, versions := range providers {
-		writeProviderVersions(bucket, path.Join(providerDirectory, name), versions)
+// WriteAPIDocuments publishes the download documents of all binaries, and the versions
+// documents of the providers, as a transaction. The documents are written with at most
+// parallelism concurrent requests. All errors are returned together, and errors of invalid
+// documents as a validationError.
+func WriteAPIDocuments(bucket *Bucket, registry *RegistryConfig, binaries versions.BinaryMetaDataList, parallelism int) error {
+	if err := assertDiscoveryDocument(bucket, registry); err != nil {
+		return err
 	}
 
+	tx := publication{bucket: bucket, registry: registry, parallelism: parallelism}
+	if err := tx.prepare(binaries); err != nil {
+		return err
+	}
+	if err := tx.validate(); err != nil {
+		return validationError{err}
+	}
+	if err := tx.commit(); err != nil {
+		tx.rollback()
+		return err
+	}
+	tx.record()
+	return nil
 }
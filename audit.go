@@ -0,0 +1,395 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/mollie/tf-provider-registry-api-generator/versions"
+	log "github.com/sirupsen/logrus"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// The kinds of inconsistencies found by the audit.
+const (
+	findingInvalidDocument  = "invalid-document"
+	findingMissingDownload  = "missing-download"
+	findingOrphanedDownload = "orphaned-download"
+	findingDanglingURL      = "dangling-url"
+	findingUnverifiableURL  = "unverifiable-url"
+)
+
+var (
+	versionsDocumentRegex = regexp.MustCompile(`^([^/]+)/([^/]+)/versions$`)
+	downloadDocumentRegex = regexp.MustCompile(`^([^/]+)/([^/]+)/([^/]+)/download/([^/]+)/([^/]+)$`)
+)
+
+// Finding is an inconsistency in the registry. The path is the document containing the
+// inconsistency, and the reference the document or object it refers to.
+type Finding struct {
+	Kind      string `json:"kind"`
+	Path      string `json:"path"`
+	Reference string `json:"reference,omitempty"`
+	Message   string `json:"message,omitempty"`
+	Fixed     bool   `json:"fixed"`
+}
+
+// audit checks the consistency of the versions and download documents in the registry.
+type audit struct {
+	bucket      *Bucket
+	registry    *RegistryConfig
+	config      *Config
+	scheme      *versions.NamingScheme
+	parallelism int
+	versions    map[string]*versions.ProviderVersions
+	downloads   map[string]*versions.BinaryMetaData
+	providers   []string
+	paths       []string
+	invalid     map[string]bool
+	findings    []*Finding
+	mutex       sync.Mutex
+}
+
+func newAudit(bucket *Bucket, config *Config, scheme *versions.NamingScheme, parallelism int) *audit {
+	return &audit{
+		bucket:      bucket,
+		registry:    &config.Registry,
+		config:      config,
+		scheme:      scheme,
+		parallelism: parallelism,
+		versions:    make(map[string]*versions.ProviderVersions),
+		downloads:   make(map[string]*versions.BinaryMetaData),
+		invalid:     make(map[string]bool),
+		findings:    make([]*Finding, 0),
+	}
+}
+
+func (a *audit) versionsPath(name string) string {
+	return path.Join(a.registry.ProvidersPath(), name, "versions")
+}
+
+func (a *audit) downloadPath(name string, version string, platform versions.Platform) string {
+	return path.Join(a.registry.ProvidersPath(), name, version, "download", platform.Os, platform.Arch)
+}
+
+func (a *audit) addFinding(kind string, path string, reference string, message string) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	entry := log.WithFields(log.Fields{"kind": kind, "path": path})
+	if reference != "" {
+		entry = entry.WithField("reference", reference)
+	}
+	if message != "" {
+		entry = entry.WithField("reason", message)
+	}
+	entry.Warn("registry is inconsistent")
+	a.findings = append(a.findings, &Finding{Kind: kind, Path: path, Reference: reference, Message: message})
+}
+
+// load reads all versions and download documents of the registry. Invalid documents are
+// reported, and excluded from the other checks.
+func (a *audit) load() error {
+	prefix := a.registry.ProvidersPath() + "/"
+	names, err := a.bucket.List(prefix)
+	if err != nil {
+		return fmt.Errorf("failed to list objects at %s, %s", prefix, err)
+	}
+
+	tasks := make([]func() error, 0, len(names))
+	for _, name := range names {
+		name := name
+		relative := strings.TrimPrefix(name, prefix)
+		if match := versionsDocumentRegex.FindStringSubmatch(relative); match != nil {
+			var document versions.ProviderVersions
+			a.versions[path.Join(match[1], match[2])] = &document
+			a.providers = append(a.providers, path.Join(match[1], match[2]))
+			tasks = append(tasks, func() error { return a.read(name, versions.VersionsDocument, &document) })
+		} else if match := downloadDocumentRegex.FindStringSubmatch(relative); match != nil {
+			document := versions.BinaryMetaData{
+				Namespace: match[1], TypeName: match[2], Version: match[3], Os: match[4], Arch: match[5],
+			}
+			a.downloads[name] = &document
+			a.paths = append(a.paths, name)
+			tasks = append(tasks, func() error { return a.read(name, versions.DownloadDocument, &document) })
+		} else {
+			log.WithField("path", name).Debug("skipping, not a registry document")
+		}
+	}
+	if err = combineErrors(runParallel(a.parallelism, tasks)); err != nil {
+		return err
+	}
+
+	a.providers = a.withoutInvalid(a.providers, a.versionsPath)
+	a.paths = a.withoutInvalid(a.paths, func(name string) string { return name })
+	sort.Strings(a.providers)
+	sort.Strings(a.paths)
+	return nil
+}
+
+// read validates and unmarshals the document. An invalid document is reported as a finding.
+func (a *audit) read(name string, kind versions.DocumentKind, document interface{}) error {
+	content, err := a.bucket.Read(name)
+	if err != nil {
+		return fmt.Errorf("failed to read %s, %s", name, err)
+	}
+	if err = versions.ValidateDocument(kind, content); err == nil {
+		err = json.Unmarshal(content, document)
+	}
+	if err != nil {
+		a.addFinding(findingInvalidDocument, name, "", err.Error())
+		a.mutex.Lock()
+		a.invalid[name] = true
+		a.mutex.Unlock()
+	}
+	return nil
+}
+
+func (a *audit) withoutInvalid(names []string, pathOf func(string) string) []string {
+	result := make([]string, 0, len(names))
+	for _, name := range names {
+		if !a.invalid[pathOf(name)] {
+			result = append(result, name)
+		}
+	}
+	return result
+}
+
+// checkListings reports the platforms listed without a download document, and the download
+// documents which are not listed in the versions document of the provider.
+func (a *audit) checkListings() {
+	for _, name := range a.providers {
+		for _, version := range a.versions[name].Versions {
+			for _, platform := range version.Platforms {
+				if download := a.downloadPath(name, version.Version, platform); a.isMissing(download) {
+					a.addFinding(findingMissingDownload, a.versionsPath(name), download, "")
+				}
+			}
+		}
+	}
+	for _, download := range a.paths {
+		if !a.isListed(a.downloads[download]) {
+			a.addFinding(findingOrphanedDownload, download, "", "")
+		}
+	}
+}
+
+// isMissing returns true if the download document does not exist. An invalid document is
+// not missing.
+func (a *audit) isMissing(download string) bool {
+	_, ok := a.downloads[download]
+	return !ok
+}
+
+// isListed returns true if the platform of the download document is listed in the versions
+// document of the provider. If the versions document is invalid, it is assumed to be listed.
+func (a *audit) isListed(download *versions.BinaryMetaData) bool {
+	name := path.Join(download.Namespace, download.TypeName)
+	if a.invalid[a.versionsPath(name)] {
+		return true
+	}
+	providerVersions, ok := a.versions[name]
+	if !ok {
+		return false
+	}
+	version := providerVersions.FindVersion(download.Version)
+	return version != nil && versions.PlatformList(version.Platforms).Contains(versions.Platform{Os: download.Os, Arch: download.Arch})
+}
+
+func referencesOf(download *versions.BinaryMetaData) []string {
+	return []string{download.DownloadURL, download.ShasumsURL, download.ShasumsSignatureURL}
+}
+
+// referencedObjects returns the objects in the bucket referred to by the download documents.
+func (a *audit) referencedObjects() []string {
+	objects := make(map[string]bool)
+	for _, download := range a.paths {
+		for _, url := range referencesOf(a.downloads[download]) {
+			if object, ok := a.config.ReleaseFileOf(a.downloads[download], url, a.scheme); ok {
+				objects[object] = true
+			} else if url != "" {
+				log.WithField("reference", url).Debug("skipping, reference outside the registry")
+			}
+		}
+	}
+	return sortedKeys(objects)
+}
+
+// checkReferences reports the download documents referring to objects which do not exist. A
+// url of the registry which cannot be resolved to an object is reported as unverifiable, as
+// the object may exist in a directory which is not configured.
+func (a *audit) checkReferences(existing map[string]bool) {
+	for _, download := range a.paths {
+		for _, url := range referencesOf(a.downloads[download]) {
+			if object, ok := a.config.ReleaseFileOf(a.downloads[download], url, a.scheme); ok {
+				if !existing[object] {
+					a.addFinding(findingDanglingURL, download, url, "")
+				}
+			} else if a.registry.IsArtifactURL(url) {
+				a.addFinding(findingUnverifiableURL, download, url, "no release file found for the url")
+			}
+		}
+	}
+}
+
+// existingObjects returns which of the objects exist in the bucket.
+func (a *audit) existingObjects(objects []string) (map[string]bool, error) {
+	existing := make(map[string]bool, len(objects))
+	tasks := make([]func() error, 0, len(objects))
+	for _, object := range objects {
+		object := object
+		tasks = append(tasks, func() error {
+			exists, err := a.bucket.Exists(object)
+			if err != nil {
+				return fmt.Errorf("failed to check %s, %s", object, err)
+			}
+			a.mutex.Lock()
+			existing[object] = exists
+			a.mutex.Unlock()
+			return nil
+		})
+	}
+	return existing, combineErrors(runParallel(a.parallelism, tasks))
+}
+
+// planFix returns the versions documents to update, and the download documents to delete to
+// resolve the findings. Download documents which are orphaned or refer to missing objects
+// are deleted. The platforms of the deleted documents which refer to missing objects, and the
+// platforms without a download document, are removed from the versions documents. Download
+// documents with unverifiable urls are left as they are. A versions document without versions
+// is to be deleted, and returned as nil.
+func (a *audit) planFix() (map[string]*versions.ProviderVersions, []string) {
+	updates := make(map[string]*versions.ProviderVersions)
+	deletes := make(map[string]bool)
+
+	removePlatform := func(name string, version string, platform versions.Platform) {
+		providerVersions, ok := a.versions[name]
+		if !ok {
+			return
+		}
+		providerVersion := providerVersions.FindVersion(version)
+		if providerVersion == nil || !providerVersion.RemovePlatform(platform) {
+			return
+		}
+		if len(providerVersion.Platforms) == 0 {
+			providerVersions.RemoveVersion(version)
+		}
+		updates[a.versionsPath(name)] = providerVersions
+	}
+
+	for _, finding := range a.findings {
+		switch finding.Kind {
+		case findingOrphanedDownload:
+			deletes[finding.Path] = true
+		case findingDanglingURL:
+			download := a.downloads[finding.Path]
+			removePlatform(path.Join(download.Namespace, download.TypeName), download.Version, versions.Platform{Os: download.Os, Arch: download.Arch})
+			deletes[finding.Path] = true
+		}
+	}
+	for _, name := range a.providers {
+		for _, version := range append([]versions.ProviderVersion{}, a.versions[name].Versions...) {
+			for _, platform := range append([]versions.Platform{}, version.Platforms...) {
+				if a.isMissing(a.downloadPath(name, version.Version, platform)) {
+					removePlatform(name, version.Version, platform)
+				}
+			}
+		}
+	}
+
+	for name, providerVersions := range updates {
+		if len(providerVersions.Versions) == 0 {
+			updates[name] = nil
+		}
+	}
+	return updates, sortedKeys(deletes)
+}
+
+// fix resolves the findings. The versions documents are updated before the download documents
+// are deleted, so that no version refers to a deleted download document. Invalid documents
+// and unverifiable urls are not fixed.
+func (a *audit) fix() error {
+	updates, deletes := a.planFix()
+
+	tasks := make([]func() error, 0, len(updates))
+	for name, providerVersions := range updates {
+		name, providerVersions := name, providerVersions
+		tasks = append(tasks, func() error {
+			if providerVersions == nil {
+				log.WithField("path", name).Info("deleting document without versions")
+				return a.bucket.Delete(name)
+			}
+			return writeJson(a.bucket, name, providerVersions, versions.VersionsDocument, a.registry.CacheControl)
+		})
+	}
+	if err := combineErrors(runParallel(a.parallelism, tasks)); err != nil {
+		return err
+	}
+
+	tasks = make([]func() error, 0, len(deletes))
+	for _, name := range deletes {
+		name := name
+		tasks = append(tasks, func() error {
+			log.WithField("path", name).Info("deleting document")
+			if err := a.bucket.Delete(name); err != nil {
+				return fmt.Errorf("failed to delete %s, %s", name, err)
+			}
+			return nil
+		})
+	}
+	if err := combineErrors(runParallel(a.parallelism, tasks)); err != nil {
+		return err
+	}
+
+	for _, finding := range a.findings {
+		finding.Fixed = finding.Kind != findingInvalidDocument && finding.Kind != findingUnverifiableURL
+	}
+	return nil
+}
+
+// sortedKeys returns the keys of the set in order.
+func sortedKeys(set map[string]bool) []string {
+	keys := make([]string, 0, len(set))
+	for key := range set {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// auditRegistry checks the consistency of the registry, and optionally fixes the findings.
+func auditRegistry(options *Options) {
+	a := newAudit(options.bucket, options.config, options.namingScheme, options.Parallelism)
+	if err := a.load(); err != nil {
+		fatalf(exitStorageError, "%s", err)
+	}
+	a.checkListings()
+	existing, err := a.existingObjects(a.referencedObjects())
+	if err != nil {
+		fatalf(exitStorageError, "%s", err)
+	}
+	a.checkReferences(existing)
+	report.Findings = a.findings
+
+	if len(a.findings) == 0 {
+		log.Info("registry is consistent")
+		report.Status = "consistent"
+		report.Exit(exitPublished)
+	}
+	if !options.Fix {
+		report.Status = "inconsistent"
+		report.Exit(exitValidationFailed)
+	}
+	if err = a.fix(); err != nil {
+		fatalf(exitCodeOf(err), "%s", err)
+	}
+	for _, finding := range a.findings {
+		if !finding.Fixed {
+			report.Status = "inconsistent"
+			report.Exit(exitValidationFailed)
+		}
+	}
+	report.Status = "fixed"
+	report.Exit(exitPublished)
+}
@@ -0,0 +1,161 @@
+package main
+
+import (
+	"github.com/mollie/tf-provider-registry-api-generator/versions"
+	"reflect"
+	"testing"
+)
+
+func TestAudit(t *testing.T) {
+	config := &Config{Registry: RegistryConfig{URL: "https://registry.example.com"}}
+	a := newAudit(nil, config, versions.DefaultNamingScheme(), 1)
+
+	download := func(version string, os string, arch string) *versions.BinaryMetaData {
+		base := "https://registry.example.com/binaries/mollie/terraform-provider-mollie/v" + version + "/terraform-provider-mollie_" + version
+		return &versions.BinaryMetaData{
+			Namespace:           "mollie",
+			TypeName:            "mollie",
+			Version:             version,
+			Os:                  os,
+			Arch:                arch,
+			DownloadURL:         base + "_" + os + "_" + arch + ".zip",
+			ShasumsURL:          base + "_SHA256SUMS",
+			ShasumsSignatureURL: base + "_SHA256SUMS.sig",
+		}
+	}
+	var providerVersions versions.ProviderVersions
+	providerVersions.AddProviderVersion(versions.ProviderVersion{Version: "1.0.0", Platforms: []versions.Platform{{Os: "linux", Arch: "amd64"}}})
+	providerVersions.AddProviderVersion(versions.ProviderVersion{Version: "1.1.0", Platforms: []versions.Platform{{Os: "darwin", Arch: "amd64"}, {Os: "linux", Arch: "amd64"}}})
+	a.versions["mollie/mollie"] = &providerVersions
+	a.providers = []string{"mollie/mollie"}
+	a.downloads = map[string]*versions.BinaryMetaData{
+		"v1/providers/mollie/mollie/1.0.0/download/linux/amd64": download("1.0.0", "linux", "amd64"),
+		"v1/providers/mollie/mollie/1.1.0/download/linux/amd64": download("1.1.0", "linux", "amd64"),
+		"v1/providers/mollie/mollie/1.2.0/download/linux/amd64": download("1.2.0", "linux", "amd64"),
+		"v1/providers/other/other/1.0.0/download/windows/amd64": {Namespace: "other", TypeName: "other", Version: "1.0.0", Os: "windows", Arch: "amd64"},
+	}
+	a.paths = []string{
+		"v1/providers/mollie/mollie/1.0.0/download/linux/amd64",
+		"v1/providers/mollie/mollie/1.1.0/download/linux/amd64",
+		"v1/providers/mollie/mollie/1.2.0/download/linux/amd64",
+		"v1/providers/other/other/1.0.0/download/windows/amd64",
+	}
+
+	a.checkListings()
+	objects := a.referencedObjects()
+	if len(objects) != 9 {
+		t.Errorf("expected 9 referenced objects, got %v", objects)
+	}
+	existing := make(map[string]bool)
+	for _, object := range objects {
+		existing[object] = object != "binaries/mollie/terraform-provider-mollie/v1.0.0/terraform-provider-mollie_1.0.0_linux_amd64.zip"
+	}
+	a.checkReferences(existing)
+
+	expected := []*Finding{
+		{Kind: findingMissingDownload, Path: "v1/providers/mollie/mollie/versions", Reference: "v1/providers/mollie/mollie/1.1.0/download/darwin/amd64"},
+		{Kind: findingOrphanedDownload, Path: "v1/providers/mollie/mollie/1.2.0/download/linux/amd64"},
+		{Kind: findingOrphanedDownload, Path: "v1/providers/other/other/1.0.0/download/windows/amd64"},
+		{Kind: findingDanglingURL, Path: "v1/providers/mollie/mollie/1.0.0/download/linux/amd64", Reference: "https://registry.example.com/binaries/mollie/terraform-provider-mollie/v1.0.0/terraform-provider-mollie_1.0.0_linux_amd64.zip"},
+	}
+	if !reflect.DeepEqual(a.findings, expected) {
+		for _, finding := range a.findings {
+			t.Logf("%v", *finding)
+		}
+		t.Fatalf("unexpected findings")
+	}
+
+	updates, deletes := a.planFix()
+	expectedDeletes := []string{
+		"v1/providers/mollie/mollie/1.0.0/download/linux/amd64",
+		"v1/providers/mollie/mollie/1.2.0/download/linux/amd64",
+		"v1/providers/other/other/1.0.0/download/windows/amd64",
+	}
+	if !reflect.DeepEqual(deletes, expectedDeletes) {
+		t.Errorf("expected deletes %v, got %v", expectedDeletes, deletes)
+	}
+	updated, ok := updates["v1/providers/mollie/mollie/versions"]
+	if !ok || len(updates) != 1 {
+		t.Fatalf("expected only the versions of mollie/mollie to be updated, got %v", updates)
+	}
+	expectedVersions := []versions.ProviderVersion{{Version: "1.1.0", Platforms: []versions.Platform{{Os: "linux", Arch: "amd64"}}}}
+	if !reflect.DeepEqual(updated.Versions, expectedVersions) {
+		t.Errorf("expected versions %v, got %v", expectedVersions, updated.Versions)
+	}
+}
+
+func TestAudit_filenameTemplate(t *testing.T) {
+	config := &Config{
+		Registry: RegistryConfig{
+			Bucket:              "registry",
+			URL:                 "https://registry.example.com",
+			ArtifactBase:        "https://artifacts.example.com",
+			ArtifactURLTemplate: "{artifactBase}/{namespace}/{type}/{version}/{filename}",
+		},
+		Providers: []ProviderConfig{{PrefixTemplate: "binaries/{namespace}/terraform-provider-{type}/v{version}/"}},
+	}
+	if err := config.ValidateRegistry(); err != nil {
+		t.Fatalf("invalid configuration, %s", err)
+	}
+	a := newAudit(nil, config, versions.DefaultNamingScheme(), 1)
+
+	download := func(namespace string, version string) *versions.BinaryMetaData {
+		base := "https://artifacts.example.com/" + namespace + "/mollie/" + version + "/terraform-provider-mollie_" + version
+		return &versions.BinaryMetaData{
+			Namespace:           namespace,
+			TypeName:            "mollie",
+			Version:             version,
+			Os:                  "linux",
+			Arch:                "amd64",
+			Filename:            "terraform-provider-mollie_" + version + "_linux_amd64.zip",
+			DownloadURL:         base + "_linux_amd64.zip",
+			ShasumsURL:          base + "_SHA256SUMS",
+			ShasumsSignatureURL: base + "_SHA256SUMS.sig",
+		}
+	}
+	var providerVersions versions.ProviderVersions
+	providerVersions.AddProviderVersion(versions.ProviderVersion{Version: "1.0.0", Platforms: []versions.Platform{{Os: "linux", Arch: "amd64"}}})
+	providerVersions.AddProviderVersion(versions.ProviderVersion{Version: "1.1.0", Platforms: []versions.Platform{{Os: "linux", Arch: "amd64"}}})
+	a.versions["mollie/mollie"] = &providerVersions
+	a.providers = []string{"mollie/mollie"}
+	unresolved := download("mollie", "1.1.0")
+	unresolved.DownloadURL = "https://artifacts.example.com/mirror/mollie/1.1.0/terraform-provider-mollie_1.1.0_linux_amd64.zip"
+	a.downloads = map[string]*versions.BinaryMetaData{
+		"v1/providers/mollie/mollie/1.0.0/download/linux/amd64": download("mollie", "1.0.0"),
+		"v1/providers/mollie/mollie/1.1.0/download/linux/amd64": unresolved,
+	}
+	a.paths = []string{
+		"v1/providers/mollie/mollie/1.0.0/download/linux/amd64",
+		"v1/providers/mollie/mollie/1.1.0/download/linux/amd64",
+	}
+
+	objects := a.referencedObjects()
+	expectedObjects := []string{
+		"binaries/mollie/terraform-provider-mollie/v1.0.0/terraform-provider-mollie_1.0.0_SHA256SUMS",
+		"binaries/mollie/terraform-provider-mollie/v1.0.0/terraform-provider-mollie_1.0.0_SHA256SUMS.sig",
+		"binaries/mollie/terraform-provider-mollie/v1.0.0/terraform-provider-mollie_1.0.0_linux_amd64.zip",
+		"binaries/mollie/terraform-provider-mollie/v1.1.0/terraform-provider-mollie_1.1.0_SHA256SUMS",
+		"binaries/mollie/terraform-provider-mollie/v1.1.0/terraform-provider-mollie_1.1.0_SHA256SUMS.sig",
+	}
+	if !reflect.DeepEqual(objects, expectedObjects) {
+		t.Fatalf("expected referenced objects %v, got %v", expectedObjects, objects)
+	}
+	existing := make(map[string]bool)
+	for _, object := range objects {
+		existing[object] = true
+	}
+	a.checkReferences(existing)
+
+	expected := []*Finding{
+		{Kind: findingUnverifiableURL, Path: "v1/providers/mollie/mollie/1.1.0/download/linux/amd64", Reference: "https://artifacts.example.com/mirror/mollie/1.1.0/terraform-provider-mollie_1.1.0_linux_amd64.zip", Message: "no release file found for the url"},
+	}
+	if !reflect.DeepEqual(a.findings, expected) {
+		for _, finding := range a.findings {
+			t.Logf("%v", *finding)
+		}
+		t.Fatalf("unexpected findings")
+	}
+	if updates, deletes := a.planFix(); len(updates) != 0 || len(deletes) != 0 {
+		t.Errorf("expected unverifiable urls not to be fixed, got updates %v and deletes %v", updates, deletes)
+	}
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"github.com/mollie/tf-provider-registry-api-generator/client"
+	"github.com/mollie/tf-provider-registry-api-generator/versions"
+	log "github.com/sirupsen/logrus"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// The steps terraform init performs to install a provider for a platform, after the discovery
+// and versions documents are read.
+const (
+	stepDownload  = "download"
+	stepFetch     = "fetch"
+	stepSignature = "signature"
+	stepChecksum  = "checksum"
+)
+
+// CheckResult is the outcome of installing a provider version for a platform.
+type CheckResult struct {
+	Provider string `json:"provider"`
+	Version  string `json:"version"`
+	Platform string `json:"platform"`
+	Passed   bool   `json:"passed"`
+	Step     string `json:"failed_step,omitempty"`
+	Message  string `json:"message,omitempty"`
+}
+
+// checkPlatform performs the steps of terraform init to install the provider version for the
+// platform.
+func checkPlatform(ctx context.Context, c *client.Client, namespace string, typeName string, version string, platform versions.Platform) *CheckResult {
+	result := &CheckResult{
+		Provider: namespace + "/" + typeName,
+		Version:  version,
+		Platform: platform.String(),
+	}
+	fail := func(step string, err error) *CheckResult {
+		result.Step = step
+		result.Message = err.Error()
+		return result
+	}
+
+	metadata, err := c.Download(ctx, namespace, typeName, version, platform)
+	if err != nil {
+		return fail(stepDownload, err)
+	}
+	pkg, err := c.Fetch(ctx, metadata)
+	if err != nil {
+		return fail(stepFetch, err)
+	}
+	if err = pkg.Verify(); err != nil {
+		if pkg.SigningKey == nil {
+			return fail(stepSignature, err)
+		}
+		return fail(stepChecksum, err)
+	}
+	result.Passed = true
+	return result
+}
+
+// checkProviderVersion installs the provider version for each of its platforms, and returns
+// the results ordered by platform.
+func checkProviderVersion(ctx context.Context, c *client.Client, namespace string, typeName string, version *versions.ProviderVersion, parallelism int) []*CheckResult {
+	platforms := append(versions.PlatformList{}, version.Platforms...)
+	sort.Sort(platforms)
+
+	results := make([]*CheckResult, len(platforms))
+	tasks := make([]func() error, 0, len(platforms))
+	for i, platform := range platforms {
+		i, platform := i, platform
+		tasks = append(tasks, func() error {
+			results[i] = checkPlatform(ctx, c, namespace, typeName, version.Version, platform)
+			return nil
+		})
+	}
+	runParallel(parallelism, tasks)
+	return results
+}
+
+// writeCheckResults writes the results as a pass/fail matrix.
+func writeCheckResults(w io.Writer, results []*CheckResult) {
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(tw, "PROVIDER\tVERSION\tPLATFORM\tRESULT\tFAILED STEP\tMESSAGE")
+	for _, r := range results {
+		outcome := "pass"
+		if !r.Passed {
+			outcome = "FAIL"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\n", r.Provider, r.Version, r.Platform, outcome, r.Step, r.Message)
+	}
+	tw.Flush()
+}
+
+// checkProvider installs the provider from the registry on the host for every platform, the
+// way terraform init does, reports the results and exits.
+func checkProvider(host string, provider string, constraint string, timeout time.Duration, parallelism int) {
+	parts := strings.Split(provider, "/")
+	if len(parts) != 2 {
+		fatalf(exitError, "provider %s is not of the form namespace/type", provider)
+	}
+	namespace, typeName := parts[0], parts[1]
+	if err := versions.ValidateNamespace(namespace); err != nil {
+		fatalf(exitError, "%s", err)
+	}
+	if err := versions.ValidateTypeName(typeName); err != nil {
+		fatalf(exitError, "%s", err)
+	}
+	if constraint == "" {
+		constraint = ">= 0"
+	}
+
+	c, err := client.New(host, newHTTPClient(timeout))
+	if err != nil {
+		fatalf(exitError, "%s", err)
+	}
+	ctx := context.Background()
+	if _, err = c.Discover(ctx); err != nil {
+		report.Status = "failed"
+		fatalf(exitValidationFailed, "service discovery failed, %s", err)
+	}
+	version, err := c.ResolveVersion(ctx, namespace, typeName, constraint, nil)
+	if err != nil {
+		report.Status = "failed"
+		fatalf(exitValidationFailed, "%s", err)
+	}
+
+	results := checkProviderVersion(ctx, c, namespace, typeName, version, parallelism)
+	report.Checks = results
+	exitCode := exitPublished
+	for _, r := range results {
+		if !r.Passed {
+			log.WithFields(log.Fields{"platform": r.Platform, "step": r.Step}).Errorf("%s", r.Message)
+			exitCode = exitValidationFailed
+		}
+	}
+	if !report.json {
+		writeCheckResults(os.Stdout, results)
+	}
+
+	if exitCode == exitPublished {
+		report.Status = "passed"
+	} else {
+		report.Status = "failed"
+	}
+	report.Exit(exitCode)
+}
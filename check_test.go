@@ -0,0 +1,111 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"github.com/mollie/tf-provider-registry-api-generator/client"
+	"github.com/mollie/tf-provider-registry-api-generator/internal/registrytest"
+	"github.com/mollie/tf-provider-registry-api-generator/versions"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestCheckProviderVersion(t *testing.T) {
+	files := map[string]string{
+		"/.well-known/terraform.json": `{"providers.v1": "/v1/providers/"}`,
+		"/v1/providers/mollie/mollie/1.0.0/download/linux/amd64": `{
+  "protocols": ["5.0"], "os": "linux", "arch": "amd64",
+  "filename": "terraform-provider-mollie_1.0.0_linux_amd64.zip",
+  "download_url": "/binaries/terraform-provider-mollie_1.0.0_linux_amd64.zip",
+  "shasums_url": "/binaries/terraform-provider-mollie_1.0.0_SHA256SUMS",
+  "shasums_signature_url": "/binaries/terraform-provider-mollie_1.0.0_SHA256SUMS.sig",
+  "shasum": "a2c5881ea67e1c397cb26c6162d81829e058d5a993801bcb69df9982412d27e9",
+  "signing_keys": {"gpg_public_keys": []}
+}`,
+		"/binaries/terraform-provider-mollie_1.0.0_linux_amd64.zip": "archive",
+		"/binaries/terraform-provider-mollie_1.0.0_SHA256SUMS":      "",
+		"/binaries/terraform-provider-mollie_1.0.0_SHA256SUMS.sig":  "",
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		content, ok := files[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(content))
+	}))
+	defer server.Close()
+
+	c, err := client.New(server.URL, server.Client())
+	if err != nil {
+		t.Fatalf("failed to create client, %s", err)
+	}
+	version := versions.ProviderVersion{
+		Version:   "1.0.0",
+		Protocols: []string{"5.0"},
+		Platforms: []versions.Platform{{Os: "linux", Arch: "amd64"}, {Os: "darwin", Arch: "amd64"}},
+	}
+	results := checkProviderVersion(context.Background(), c, "mollie", "mollie", &version, 2)
+	if len(results) != 2 {
+		t.Fatalf("expected 2 results, got %d", len(results))
+	}
+	if results[0].Platform != "darwin_amd64" || results[0].Passed || results[0].Step != stepDownload {
+		t.Errorf("expected darwin_amd64 to fail to download, got %+v", results[0])
+	}
+	if results[1].Platform != "linux_amd64" || results[1].Passed || results[1].Step != stepSignature {
+		t.Errorf("expected linux_amd64 to fail the signature, got %+v", results[1])
+	}
+
+	var out bytes.Buffer
+	writeCheckResults(&out, results)
+	if lines := strings.Split(strings.TrimSpace(out.String()), "\n"); len(lines) != 3 || !strings.Contains(lines[2], "FAIL") {
+		t.Errorf("unexpected matrix\n%s", out.String())
+	}
+}
+
+func TestCheckProviderVersion_signed(t *testing.T) {
+	server := registrytest.New(t, "registry", registrytest.Release{
+		Namespace: "mollie",
+		TypeName:  "mollie",
+		Version:   "1.0.0",
+		Platforms: []versions.Platform{{Os: "linux", Arch: "amd64"}, {Os: "linux", Arch: "arm64"}},
+		Tampered:  []versions.Platform{{Os: "linux", Arch: "arm64"}},
+		Missing:   []versions.Platform{{Os: "darwin", Arch: "amd64"}},
+	})
+	c, err := client.New(server.URL, server.Client())
+	if err != nil {
+		t.Fatalf("failed to create client, %s", err)
+	}
+	version, err := c.ResolveVersion(context.Background(), "mollie", "mollie", "1.0.0", nil)
+	if err != nil {
+		t.Fatalf("failed to resolve version, %s", err)
+	}
+
+	results := checkProviderVersion(context.Background(), c, "mollie", "mollie", version, 2)
+	expected := []struct {
+		platform string
+		passed   bool
+		step     string
+	}{
+		{"darwin_amd64", false, stepDownload},
+		{"linux_amd64", true, ""},
+		{"linux_arm64", false, stepChecksum},
+	}
+	if len(results) != len(expected) {
+		t.Fatalf("expected %d results, got %d", len(expected), len(results))
+	}
+	for i, e := range expected {
+		if r := results[i]; r.Platform != e.platform || r.Passed != e.passed || r.Step != e.step {
+			t.Errorf("expected %s to have passed %v at step '%s', got %+v", e.platform, e.passed, e.step, r)
+		}
+	}
+
+	var out bytes.Buffer
+	writeCheckResults(&out, results)
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 4 || !strings.Contains(lines[1], "FAIL") || !strings.Contains(lines[2], "pass") || !strings.Contains(lines[3], "checksum") {
+		t.Errorf("unexpected matrix\n%s", out.String())
+	}
+}
@@ -0,0 +1,250 @@
+// Package client implements the client side of the Terraform provider registry protocol. It
+// performs the same requests as Terraform does to install a provider, so that a registry can
+// be tested the way Terraform sees it.
+package client
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	goversion "github.com/hashicorp/go-version"
+	"github.com/mollie/tf-provider-registry-api-generator/signing_key"
+	"github.com/mollie/tf-provider-registry-api-generator/versions"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+)
+
+// Client accesses the provider registry of a host.
+type Client struct {
+	HTTPClient *http.Client
+	Host       *url.URL
+	providers  *url.URL
+}
+
+// StatusError is returned when the registry responds with an unexpected status code.
+type StatusError struct {
+	URL        string
+	StatusCode int
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("GET %s returned %d %s", e.URL, e.StatusCode, http.StatusText(e.StatusCode))
+}
+
+// Package is a provider archive with its SHA256SUMS and signature, as downloaded by Terraform.
+type Package struct {
+	Metadata   *versions.BinaryMetaData
+	Archive    []byte
+	Shasums    []byte
+	Signature  []byte
+	SigningKey *versions.GpgSigningKey
+}
+
+// New returns a client for the registry on the host. The host is a hostname like
+// registry.example.com, or a url like http://localhost:8080.
+func New(host string, httpClient *http.Client) (*Client, error) {
+	if !strings.Contains(host, "://") {
+		host = "https://" + host
+	}
+	hostURL, err := url.Parse(host)
+	if err != nil {
+		return nil, fmt.Errorf("invalid host %s, %s", host, err)
+	}
+	if hostURL.Host == "" {
+		return nil, fmt.Errorf("invalid host %s", host)
+	}
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	return &Client{HTTPClient: httpClient, Host: hostURL}, nil
+}
+
+func (c *Client) get(ctx context.Context, location *url.URL) ([]byte, error) {
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, location.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	response, err := c.HTTPClient.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return nil, &StatusError{URL: location.String(), StatusCode: response.StatusCode}
+	}
+	return ioutil.ReadAll(response.Body)
+}
+
+func (c *Client) getJSON(ctx context.Context, location *url.URL, object interface{}) error {
+	content, err := c.get(ctx, location)
+	if err != nil {
+		return err
+	}
+	if err = json.Unmarshal(content, object); err != nil {
+		return fmt.Errorf("invalid response from %s, %s", location, err)
+	}
+	return nil
+}
+
+// Discover returns the url of the providers API from the service discovery document of
+// the host. The result is cached.
+func (c *Client) Discover(ctx context.Context) (*url.URL, error) {
+	if c.providers != nil {
+		return c.providers, nil
+	}
+
+	discovery := c.Host.ResolveReference(&url.URL{Path: "/.well-known/terraform.json"})
+	services := make(map[string]interface{})
+	if err := c.getJSON(ctx, discovery, &services); err != nil {
+		return nil, err
+	}
+	providers, ok := services["providers.v1"].(string)
+	if !ok || providers == "" {
+		return nil, fmt.Errorf("%s does not offer the providers.v1 service", c.Host.Host)
+	}
+	if !strings.HasSuffix(providers, "/") {
+		providers += "/"
+	}
+	reference, err := url.Parse(providers)
+	if err != nil {
+		return nil, fmt.Errorf("invalid providers.v1 url %s, %s", providers, err)
+	}
+	c.providers = discovery.ResolveReference(reference)
+	return c.providers, nil
+}
+
+func (c *Client) providerURL(ctx context.Context, elements ...string) (*url.URL, error) {
+	providers, err := c.Discover(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return providers.ResolveReference(&url.URL{Path: path.Join(elements...)}), nil
+}
+
+// Versions returns the available versions of the provider.
+func (c *Client) Versions(ctx context.Context, namespace string, typeName string) (*versions.ProviderVersions, error) {
+	location, err := c.providerURL(ctx, namespace, typeName, "versions")
+	if err != nil {
+		return nil, err
+	}
+	var result versions.ProviderVersions
+	if err = c.getJSON(ctx, location, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// ResolveVersion returns the newest version of the provider which matches the constraint,
+// like ">= 1.0, < 2.0" or "~> 1.2". If the platform is not nil, only versions available for
+// the platform are considered.
+func (c *Client) ResolveVersion(ctx context.Context, namespace string, typeName string, constraint string, platform *versions.Platform) (*versions.ProviderVersion, error) {
+	if _, err := goversion.NewConstraint(constraint); err != nil {
+		return nil, fmt.Errorf("invalid version constraint %s, %s", constraint, err)
+	}
+	providerVersions, err := c.Versions(ctx, namespace, typeName)
+	if err != nil {
+		return nil, err
+	}
+	result, err := providerVersions.Resolve(constraint, platform)
+	if err != nil {
+		return nil, fmt.Errorf("no version of %s/%s matches %s", namespace, typeName, constraint)
+	}
+	return result, nil
+}
+
+// Download returns the download document of the provider version for the platform. The
+// urls in the document are resolved relative to the document.
+func (c *Client) Download(ctx context.Context, namespace string, typeName string, version string, platform versions.Platform) (*versions.BinaryMetaData, error) {
+	location, err := c.providerURL(ctx, namespace, typeName, version, "download", platform.Os, platform.Arch)
+	if err != nil {
+		return nil, err
+	}
+	var result versions.BinaryMetaData
+	if err = c.getJSON(ctx, location, &result); err != nil {
+		return nil, err
+	}
+	for _, reference := range []*string{&result.DownloadURL, &result.ShasumsURL, &result.ShasumsSignatureURL} {
+		resolved, err := url.Parse(*reference)
+		if err != nil {
+			return nil, fmt.Errorf("invalid url %s in %s, %s", *reference, location, err)
+		}
+		*reference = location.ResolveReference(resolved).String()
+	}
+	result.Namespace = namespace
+	result.TypeName = typeName
+	result.Version = version
+	return &result, nil
+}
+
+// FetchPackage downloads the archive, the SHA256SUMS and its signature of the download
+// document, and verifies them.
+func (c *Client) FetchPackage(ctx context.Context, metadata *versions.BinaryMetaData) (*Package, error) {
+	result, err := c.Fetch(ctx, metadata)
+	if err != nil {
+		return nil, err
+	}
+	if err = result.Verify(); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// Fetch downloads the archive, the SHA256SUMS and its signature of the download document,
+// without verifying them.
+func (c *Client) Fetch(ctx context.Context, metadata *versions.BinaryMetaData) (*Package, error) {
+	result := Package{Metadata: metadata}
+	for _, download := range []struct {
+		location string
+		content  *[]byte
+	}{
+		{metadata.DownloadURL, &result.Archive},
+		{metadata.ShasumsURL, &result.Shasums},
+		{metadata.ShasumsSignatureURL, &result.Signature},
+	} {
+		location, err := url.Parse(download.location)
+		if err != nil {
+			return nil, fmt.Errorf("invalid url %s, %s", download.location, err)
+		}
+		if *download.content, err = c.get(ctx, location); err != nil {
+			return nil, err
+		}
+	}
+	return &result, nil
+}
+
+// Verify checks the package the way Terraform does: the SHA256SUMS must be signed by one of
+// the signing keys in the download document, and list the shasum of the archive.
+func (p *Package) Verify() error {
+	p.SigningKey = nil
+	for i, key := range p.Metadata.SigningKeys.GpgPublicKeys {
+		if signing_key.VerifySignature(key.ASCIIArmor, p.Shasums, p.Signature) == nil {
+			p.SigningKey = &p.Metadata.SigningKeys.GpgPublicKeys[i]
+			break
+		}
+	}
+	if p.SigningKey == nil {
+		return fmt.Errorf("the signature of %s is not valid for any of the signing keys", p.Metadata.ShasumsURL)
+	}
+
+	shasums := make(map[string]string)
+	if err := versions.ParseShasums(bytes.NewReader(p.Shasums), shasums); err != nil {
+		return fmt.Errorf("invalid SHA256SUMS %s, %s", p.Metadata.ShasumsURL, err)
+	}
+	if shasum, ok := shasums[p.Metadata.Filename]; !ok {
+		return fmt.Errorf("%s does not list %s", p.Metadata.ShasumsURL, p.Metadata.Filename)
+	} else if shasum != strings.ToLower(p.Metadata.Shasum) {
+		return fmt.Errorf("the shasum of %s in %s is %s, the download document says %s", p.Metadata.Filename, p.Metadata.ShasumsURL, shasum, p.Metadata.Shasum)
+	}
+
+	digest := sha256.Sum256(p.Archive)
+	if actual := hex.EncodeToString(digest[:]); actual != strings.ToLower(p.Metadata.Shasum) {
+		return fmt.Errorf("the shasum of %s is %s, expected %s", p.Metadata.DownloadURL, actual, p.Metadata.Shasum)
+	}
+	return nil
+}
@@ -0,0 +1,103 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"github.com/mollie/tf-provider-registry-api-generator/internal/registrytest"
+	"github.com/mollie/tf-provider-registry-api-generator/versions"
+	"net/http"
+	"testing"
+)
+
+// newRegistry returns a registry serving the versions 1.0.0, 1.1.0 and 2.0.0 of mollie/mollie
+// for linux_amd64. The archive of 2.0.0 does not match its shasum.
+func newRegistry(t *testing.T) *registrytest.Registry {
+	linux := []versions.Platform{{Os: "linux", Arch: "amd64"}}
+	return registrytest.New(t, "registry",
+		registrytest.Release{Namespace: "mollie", TypeName: "mollie", Version: "1.0.0", Platforms: linux},
+		registrytest.Release{Namespace: "mollie", TypeName: "mollie", Version: "1.1.0", Platforms: linux},
+		registrytest.Release{Namespace: "mollie", TypeName: "mollie", Version: "2.0.0", Platforms: linux, Tampered: linux},
+	)
+}
+
+func TestClient(t *testing.T) {
+	server := newRegistry(t)
+	ctx := context.Background()
+
+	c, err := New(server.URL, server.Client())
+	if err != nil {
+		t.Fatalf("failed to create client, %s", err)
+	}
+	providers, err := c.Discover(ctx)
+	if err != nil || providers.String() != server.URL+"/v1/providers/" {
+		t.Fatalf("expected providers at %s/v1/providers/, got %s, %v", server.URL, providers, err)
+	}
+
+	platform := versions.Platform{Os: "linux", Arch: "amd64"}
+	tests := []struct {
+		constraint string
+		want       string
+	}{
+		{"~> 1.0", "1.1.0"},
+		{">= 1.0, < 1.1", "1.0.0"},
+		{">= 1.0", "2.0.0"},
+		{"> 2.0", ""},
+	}
+	for _, tt := range tests {
+		version, err := c.ResolveVersion(ctx, "mollie", "mollie", tt.constraint, &platform)
+		if tt.want == "" {
+			if err == nil {
+				t.Errorf("expected no version to match %s, got %s", tt.constraint, version.Version)
+			}
+			continue
+		}
+		if err != nil || version.Version != tt.want {
+			t.Errorf("expected %s to resolve to %s, got %v, %v", tt.constraint, tt.want, version, err)
+		}
+	}
+	if _, err = c.ResolveVersion(ctx, "mollie", "mollie", "~> 1.0", &versions.Platform{Os: "darwin", Arch: "amd64"}); err == nil {
+		t.Errorf("expected no version for darwin_amd64")
+	}
+
+	metadata, err := c.Download(ctx, "mollie", "mollie", "1.1.0", platform)
+	if err != nil {
+		t.Fatalf("failed to get download document, %s", err)
+	}
+	if metadata.DownloadURL != server.URL+"/binaries/mollie/terraform-provider-mollie/v1.1.0/terraform-provider-mollie_1.1.0_linux_amd64.zip" {
+		t.Errorf("expected the download url to be resolved, got %s", metadata.DownloadURL)
+	}
+	pkg, err := c.FetchPackage(ctx, metadata)
+	if err != nil {
+		t.Fatalf("expected a valid package, %s", err)
+	}
+	if pkg.SigningKey == nil || string(pkg.Archive) != "archive of 1.1.0 for linux_amd64" {
+		t.Errorf("unexpected package %v", pkg)
+	}
+
+	metadata, err = c.Download(ctx, "mollie", "mollie", "2.0.0", platform)
+	if err != nil {
+		t.Fatalf("failed to get download document, %s", err)
+	}
+	if _, err = c.FetchPackage(ctx, metadata); err == nil {
+		t.Errorf("expected the tampered archive to be rejected")
+	}
+
+	metadata, err = c.Download(ctx, "mollie", "mollie", "1.0.0", platform)
+	if err != nil {
+		t.Fatalf("failed to get download document, %s", err)
+	}
+	pkg, err = c.FetchPackage(ctx, metadata)
+	if err != nil {
+		t.Fatalf("expected a valid package, %s", err)
+	}
+	pkg.Signature = []byte("invalid")
+	if err = pkg.Verify(); err == nil {
+		t.Errorf("expected the invalid signature to be rejected")
+	}
+
+	_, err = c.Versions(ctx, "mollie", "unknown")
+	var statusError *StatusError
+	if !errors.As(err, &statusError) || statusError.StatusCode != http.StatusNotFound {
+		t.Errorf("expected a 404 for an unknown provider, got %v", err)
+	}
+}
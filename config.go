@@ -0,0 +1,280 @@
+package main
+
+import (
+	"fmt"
+	"github.com/mollie/tf-provider-registry-api-generator/signing_key"
+	"github.com/mollie/tf-provider-registry-api-generator/versions"
+	"gopkg.in/yaml.v2"
+	"io/ioutil"
+	"path"
+	"strings"
+)
+
+const defaultCacheControl = "no-cache, max-age=60"
+
+// Config describes the registry and the providers to publish. It is read from a YAML or JSON
+// file, or created from the command line options.
+type Config struct {
+	Registry  RegistryConfig   `yaml:"registry"`
+	Providers []ProviderConfig `yaml:"providers"`
+}
+
+// RegistryConfig describes where and how the registry is hosted. The release files are served
+// from the artifact base url, which defaults to the url of the registry.
+type RegistryConfig struct {
+	Backend             string             `yaml:"backend"`
+	Bucket              string             `yaml:"bucket"`
+	URL                 string             `yaml:"url"`
+	BasePath            string             `yaml:"base_path"`
+	CacheControl        string             `yaml:"cache_control"`
+	SigningKeys         []SigningKeyConfig `yaml:"signing_keys"`
+	ArtifactBase        string             `yaml:"artifact_base"`
+	ArtifactURLTemplate string             `yaml:"artifact_url"`
+	artifactURL         *versions.ArtifactURLTemplate
+}
+
+// SigningKeyConfig describes a public key used to sign the releases.
+type SigningKeyConfig struct {
+	Fingerprint        string `yaml:"fingerprint"`
+	Source             string `yaml:"source"`
+	SourceURL          string `yaml:"source_url"`
+	TrustSignatureFile string `yaml:"trust_signature_file"`
+}
+
+// ProviderConfig describes the releases of the providers in a namespace, or with a prefix
+// template, the releases of the providers in all namespaces matching the template.
+type ProviderConfig struct {
+	Namespace      string   `yaml:"namespace"`
+	Prefix         string   `yaml:"prefix"`
+	PrefixTemplate string   `yaml:"prefix_template"`
+	Protocols      []string `yaml:"protocols"`
+	prefixTemplate *versions.PrefixTemplate
+}
+
+// ListPrefix returns the prefix of the objects to list.
+func (p *ProviderConfig) ListPrefix() string {
+	if p.prefixTemplate != nil {
+		return p.prefixTemplate.ListPrefix()
+	}
+	return strings.Trim(p.Prefix, "/") + "/"
+}
+
+// LoadConfig reads the configuration from a YAML or JSON file.
+func LoadConfig(filename string) (*Config, error) {
+	content, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s, %s", filename, err)
+	}
+	var config Config
+	if err = yaml.UnmarshalStrict(content, &config); err != nil {
+		return nil, fmt.Errorf("failed to parse %s, %s", filename, err)
+	}
+	return &config, nil
+}
+
+// ProvidersPath returns the path of the providers API documents in the bucket.
+func (r *RegistryConfig) ProvidersPath() string {
+	return path.Join(r.BasePath, "v1", "providers")
+}
+
+// ObjectOf returns the object in the bucket referred to by the url, or false if the url does
+// not refer to the registry or to a release file in the bucket. Urls are only mapped to objects
+// if the artifact url template refers to files by their path; urls of the registry itself are
+// accepted as well, for documents published before the artifact base was configured.
+func (r *RegistryConfig) ObjectOf(url string) (string, bool) {
+	if r.artifactURL != nil {
+		if object, ok := r.artifactURL.ObjectOf(url); ok {
+			return object, true
+		}
+		if !r.artifactURL.HasPath() {
+			return "", false
+		}
+	}
+	prefix := r.URL + "/"
+	if !strings.HasPrefix(url, prefix) {
+		return "", false
+	}
+	return strings.TrimPrefix(url, prefix), true
+}
+
+// IsArtifactURL returns true if the url may refer to a release file in the bucket, because it
+// starts like the urls of the artifact url template or the url of the registry.
+func (r *RegistryConfig) IsArtifactURL(url string) bool {
+	if r.artifactURL != nil && r.artifactURL.Prefix() != "" && strings.HasPrefix(url, r.artifactURL.Prefix()) {
+		return true
+	}
+	return strings.HasPrefix(url, r.URL+"/")
+}
+
+// releaseDirectory returns the directory of the release files of the provider version, or false
+// if the provider configuration does not contain the release.
+func (p *ProviderConfig) releaseDirectory(namespace string, typeName string, version string) (string, bool) {
+	if p.prefixTemplate != nil {
+		if !p.prefixTemplate.HasNamespace() && p.Namespace != namespace {
+			return "", false
+		}
+		return p.prefixTemplate.Directory(namespace, typeName, version), true
+	}
+	if p.Namespace != namespace || strings.Trim(p.Prefix, "/") == "" {
+		return "", false
+	}
+	return strings.Trim(p.Prefix, "/"), true
+}
+
+// ReleaseFileOf returns the object in the bucket referred to by the url in the download
+// document. If the artifact url template does not refer to files by their path, the release
+// files of the provider version are looked up in the directories of the providers, and a file
+// is only returned if the template yields the url for it. It returns false if the url cannot
+// be resolved.
+func (c *Config) ReleaseFileOf(download *versions.BinaryMetaData, url string, scheme *versions.NamingScheme) (string, bool) {
+	if object, ok := c.Registry.ObjectOf(url); ok || c.Registry.artifactURL == nil {
+		return object, ok
+	}
+	names := []string{
+		download.Filename,
+		scheme.ShasumsFileName(download.TypeName, download.Version),
+		scheme.SignatureFileName(download.TypeName, download.Version),
+		path.Base(url),
+	}
+	for i := range c.Providers {
+		directory, ok := c.Providers[i].releaseDirectory(download.Namespace, download.TypeName, download.Version)
+		if !ok {
+			continue
+		}
+		for _, name := range names {
+			object := path.Join(directory, name)
+			if name != "" && c.Registry.artifactURL.URL(object, download.Namespace, download.TypeName, download.Version) == url {
+				return object, true
+			}
+		}
+	}
+	return "", false
+}
+
+// ValidateRegistry checks the registry configuration, and parses the prefix templates of the
+// providers to find the release files referred to by the download documents. Unlike Validate,
+// it does not require any providers.
+func (c *Config) ValidateRegistry() error {
+	if err := c.Registry.Validate(); err != nil {
+		return err
+	}
+	for i := range c.Providers {
+		if err := c.Providers[i].parsePrefixTemplate(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// parsePrefixTemplate parses the prefix template of the provider, if any.
+func (p *ProviderConfig) parsePrefixTemplate() error {
+	if p.PrefixTemplate == "" {
+		return nil
+	}
+	template, err := versions.NewPrefixTemplate(p.PrefixTemplate)
+	if err != nil {
+		return err
+	}
+	p.prefixTemplate = template
+	return nil
+}
+
+// Validate checks the configuration and sets the defaults. The protocols are used for
+// providers without protocols.
+func (c *Config) Validate(protocols []string) error {
+	if err := c.Registry.Validate(); err != nil {
+		return err
+	}
+
+	if len(c.Providers) == 0 {
+		return fmt.Errorf("no providers specified")
+	}
+	for i := range c.Providers {
+		p := &c.Providers[i]
+		if p.PrefixTemplate != "" {
+			if p.Prefix != "" {
+				return fmt.Errorf("specify either a prefix or a prefix template, not both")
+			}
+			if err := p.parsePrefixTemplate(); err != nil {
+				return err
+			}
+		} else if strings.Trim(p.Prefix, "/") == "" {
+			return fmt.Errorf("no prefix specified for namespace %s", p.Namespace)
+		}
+		if p.prefixTemplate == nil || !p.prefixTemplate.HasNamespace() {
+			if err := versions.ValidateNamespace(p.Namespace); err != nil {
+				return err
+			}
+		}
+		if len(p.Protocols) == 0 {
+			p.Protocols = protocols
+		}
+		for _, protocol := range p.Protocols {
+			if !protocolRegex.MatchString(protocol) {
+				return fmt.Errorf("%s is not a version number", protocol)
+			}
+		}
+	}
+	return nil
+}
+
+// Validate checks the registry configuration and sets the defaults.
+func (r *RegistryConfig) Validate() error {
+	if r.Backend == "" {
+		r.Backend = "gcs"
+	}
+	if r.Backend != "gcs" {
+		return fmt.Errorf("unsupported backend '%s', only gcs is supported", r.Backend)
+	}
+	if r.Bucket == "" {
+		return fmt.Errorf("no bucket specified")
+	}
+	if r.URL == "" {
+		return fmt.Errorf("no url specified")
+	}
+	r.URL = strings.TrimRight(r.URL, "/")
+	r.BasePath = strings.Trim(r.BasePath, "/")
+	if r.CacheControl == "" {
+		r.CacheControl = defaultCacheControl
+	}
+	if r.ArtifactBase == "" {
+		r.ArtifactBase = r.URL
+	}
+	if r.ArtifactURLTemplate == "" {
+		r.ArtifactURLTemplate = versions.DefaultArtifactURLTemplate
+	}
+	artifactURL, err := versions.NewArtifactURLTemplate(r.ArtifactURLTemplate, r.ArtifactBase)
+	if err != nil {
+		return err
+	}
+	r.artifactURL = artifactURL
+	for _, key := range r.SigningKeys {
+		if key.Fingerprint == "" {
+			return fmt.Errorf("signing key without fingerprint")
+		}
+	}
+	return nil
+}
+
+// LoadSigningKeys exports the configured public keys, and reads their trust signatures.
+func (r *RegistryConfig) LoadSigningKeys() ([]signing_key.PGPSigningKey, error) {
+	result := make([]signing_key.PGPSigningKey, 0, len(r.SigningKeys))
+	for _, keyConfig := range r.SigningKeys {
+		key, err := signing_key.GetPublicSigningKey(keyConfig.Fingerprint)
+		if err != nil {
+			return nil, err
+		}
+		trustSignature := ""
+		if keyConfig.TrustSignatureFile != "" {
+			content, err := ioutil.ReadFile(keyConfig.TrustSignatureFile)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read trust signature, %s", err)
+			}
+			trustSignature = string(content)
+		}
+		keys := []signing_key.PGPSigningKey{key}
+		signing_key.SetSource(keys, keyConfig.Source, keyConfig.SourceURL, trustSignature)
+		result = append(result, keys[0])
+	}
+	return result, nil
+}
@@ -0,0 +1,201 @@
+package main
+
+import (
+	"encoding/json"
+	"github.com/mollie/tf-provider-registry-api-generator/internal/registrytest"
+	"github.com/mollie/tf-provider-registry-api-generator/versions"
+	"os"
+	"os/exec"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestLoadConfig(t *testing.T) {
+	config, err := LoadConfig("example/registry.yaml")
+	if err != nil {
+		t.Fatalf("failed to load config, %s", err)
+	}
+	if err = config.Validate([]string{"5.0"}); err != nil {
+		t.Fatalf("invalid config, %s", err)
+	}
+
+	if config.Registry.Bucket != "my-project-tf-registry" || config.Registry.ProvidersPath() != "v1/providers" {
+		t.Errorf("unexpected registry %v", config.Registry)
+	}
+	if len(config.Registry.SigningKeys) != 1 || config.Registry.SigningKeys[0].Source != "Example Inc." {
+		t.Errorf("unexpected signing keys %v", config.Registry.SigningKeys)
+	}
+	if len(config.Providers) != 2 {
+		t.Fatalf("expected 2 providers, found %d", len(config.Providers))
+	}
+	if !reflect.DeepEqual(config.Providers[0].Protocols, []string{"5.0"}) {
+		t.Errorf("expected default protocols, found %v", config.Providers[0].Protocols)
+	}
+	if !reflect.DeepEqual(config.Providers[1].Protocols, []string{"4.0"}) {
+		t.Errorf("expected protocol override, found %v", config.Providers[1].Protocols)
+	}
+}
+
+func TestConfig_Validate(t *testing.T) {
+	valid := func() Config {
+		return Config{
+			Registry:  RegistryConfig{Bucket: "registry", URL: "https://registry.example.com/", BasePath: "/api/"},
+			Providers: []ProviderConfig{{Namespace: "example", Prefix: "binaries/example"}},
+		}
+	}
+	tests := []struct {
+		name    string
+		modify  func(c *Config)
+		wantErr bool
+	}{
+		{"valid", func(c *Config) {}, false},
+		{"unsupported_backend", func(c *Config) { c.Registry.Backend = "s3" }, true},
+		{"no_bucket", func(c *Config) { c.Registry.Bucket = "" }, true},
+		{"no_providers", func(c *Config) { c.Providers = nil }, true},
+		{"invalid_namespace", func(c *Config) { c.Providers[0].Namespace = "Example" }, true},
+		{"no_prefix", func(c *Config) { c.Providers[0].Prefix = "/" }, true},
+		{"invalid_protocol", func(c *Config) { c.Providers[0].Protocols = []string{"five"} }, true},
+		{"artifact_url", func(c *Config) { c.Registry.ArtifactURLTemplate = "{artifactBase}/{namespace}/{filename}" }, false},
+		{"invalid_artifact_url", func(c *Config) { c.Registry.ArtifactURLTemplate = "{artifactBase}/{os}/{filename}" }, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := valid()
+			tt.modify(&c)
+			err := c.Validate([]string{"5.0"})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("expected error %v, got %v", tt.wantErr, err)
+			}
+			if err == nil && (c.Registry.ProvidersPath() != "api/v1/providers" || c.Registry.URL != "https://registry.example.com" || c.Registry.CacheControl != defaultCacheControl) {
+				t.Errorf("defaults not applied, %v", c.Registry)
+			}
+		})
+	}
+}
+
+func TestRegistryConfig_ObjectOf(t *testing.T) {
+	tests := []struct {
+		name     string
+		base     string
+		template string
+		url      string
+		want     string
+	}{
+		{"registry", "", "", "https://registry.example.com/binaries/a.zip", "binaries/a.zip"},
+		{"cdn", "https://cdn.example.com", "", "https://cdn.example.com/binaries/a.zip", "binaries/a.zip"},
+		{"cdn_registry_url", "https://cdn.example.com", "", "https://registry.example.com/binaries/a.zip", "binaries/a.zip"},
+		{"external", "https://artifacts.example.com", "{artifactBase}/{namespace}/{type}/{version}/{filename}", "https://artifacts.example.com/mollie/mollie/1.0.0/a.zip", ""},
+		{"other_host", "", "", "https://example.com/binaries/a.zip", ""},
+		{"external_registry_url", "https://artifacts.example.com", "{artifactBase}/{namespace}/{path}", "https://registry.example.com/binaries/a.zip", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := RegistryConfig{Bucket: "registry", URL: "https://registry.example.com", ArtifactBase: tt.base, ArtifactURLTemplate: tt.template}
+			if err := r.Validate(); err != nil {
+				t.Fatalf("invalid registry, %s", err)
+			}
+			object, ok := r.ObjectOf(tt.url)
+			if object != tt.want || ok != (tt.want != "") {
+				t.Errorf("expected %q, got %q, %v", tt.want, object, ok)
+			}
+		})
+	}
+}
+
+func TestConfigFromOptions_trustSignature(t *testing.T) {
+	tests := []struct {
+		name           string
+		fingerprint    string
+		trustSignature string
+		wantErr        bool
+	}{
+		{"single_fingerprint", "A1B2C3D4E5F6A7B8", "trust.asc", false},
+		{"multiple_fingerprints", "A1B2C3D4E5F6A7B8, B1B2C3D4E5F6A7B8", "", false},
+		{"trust_signature_for_multiple_fingerprints", "A1B2C3D4E5F6A7B8,B1B2C3D4E5F6A7B8", "trust.asc", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			options := Options{Fingerprint: tt.fingerprint, TrustSignature: tt.trustSignature}
+			config, err := configFromOptions(&options)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("expected error %v, got %v", tt.wantErr, err)
+			}
+			if err == nil && len(config.Registry.SigningKeys) != len(strings.Split(tt.fingerprint, ",")) {
+				t.Errorf("unexpected signing keys %v", config.Registry.SigningKeys)
+			}
+		})
+	}
+}
+
+func TestLoadSigningKeys_shortKeyID(t *testing.T) {
+	if _, err := exec.LookPath("gpg"); err != nil {
+		t.Skip("gpg is not installed")
+	}
+	home := t.TempDir()
+	defer os.Setenv("GNUPGHOME", os.Getenv("GNUPGHOME"))
+	os.Setenv("GNUPGHOME", home)
+
+	key := registrytest.NewSigningKey(t, "publisher")
+	publicKey, err := key.PublicKey()
+	if err != nil {
+		t.Fatalf("failed to export public key, %s", err)
+	}
+	cmd := exec.Command("gpg", "--batch", "--import")
+	cmd.Stdin = strings.NewReader(publicKey.ASCIIArmor)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("failed to import public key, %s %s", err, output)
+	}
+
+	config, err := configFromOptions(&Options{Fingerprint: key.Fingerprint()[32:]})
+	if err != nil {
+		t.Fatalf("unexpected error, %s", err)
+	}
+	signingKeys, err := config.Registry.LoadSigningKeys()
+	if err != nil {
+		t.Fatalf("failed to load signing keys, %s", err)
+	}
+	if len(signingKeys) != 1 || signingKeys[0].KeyID != key.Fingerprint() {
+		t.Fatalf("expected the key id %s, got %v", key.Fingerprint(), signingKeys)
+	}
+
+	download := versions.BinaryMetaData{
+		Protocols:           []string{"5.0"},
+		Os:                  "linux",
+		Arch:                "amd64",
+		Filename:            "terraform-provider-mollie_1.0.0_linux_amd64.zip",
+		DownloadURL:         "https://registry.example.com/terraform-provider-mollie_1.0.0_linux_amd64.zip",
+		ShasumsURL:          "https://registry.example.com/terraform-provider-mollie_1.0.0_SHA256SUMS",
+		ShasumsSignatureURL: "https://registry.example.com/terraform-provider-mollie_1.0.0_SHA256SUMS.sig",
+		Shasum:              strings.Repeat("a", 64),
+	}
+	download.SetPGPSigningKeys(signingKeys)
+	content, err := json.Marshal(&download)
+	if err != nil {
+		t.Fatalf("failed to marshal download document, %s", err)
+	}
+	if err = versions.ValidateDocument(versions.DownloadDocument, content); err != nil {
+		t.Errorf("invalid download document, %s", err)
+	}
+}
+
+func TestCheckConfigOptions(t *testing.T) {
+	tests := []struct {
+		name    string
+		options Options
+		wantErr bool
+	}{
+		{"config", Options{Config: "registry.yaml"}, false},
+		{"fingerprint", Options{Config: "registry.yaml", Fingerprint: "A1B2C3D4E5F6A7B8"}, false},
+		{"bucket_name", Options{Config: "registry.yaml", BucketName: "registry"}, true},
+		{"url", Options{Config: "registry.yaml", Url: "https://registry.example.com"}, true},
+		{"prefix", Options{Config: "registry.yaml", Namespace: "mollie", Prefix: "binaries"}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := checkConfigOptions(&tt.options); (err != nil) != tt.wantErr {
+				t.Errorf("expected error %v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,180 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/mollie/tf-provider-registry-api-generator/versions"
+	log "github.com/sirupsen/logrus"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// distArtifact is an entry of the artifacts.json written by goreleaser.
+type distArtifact struct {
+	Name   string `json:"name"`
+	Path   string `json:"path"`
+	Goos   string `json:"goos"`
+	Goarch string `json:"goarch"`
+	Type   string `json:"type"`
+}
+
+// distMetadata is the metadata.json written by goreleaser.
+type distMetadata struct {
+	ProjectName string `json:"project_name"`
+	Tag         string `json:"tag"`
+	Version     string `json:"version"`
+}
+
+// distRelease is the provider release in a goreleaser dist directory.
+type distRelease struct {
+	TypeName string
+	Version  string
+	// Files are the names of the archives, SHA256SUMS, signature and manifest.
+	Files     []string
+	directory string
+}
+
+// distArtifactTypes are the types of the artifacts which are part of a provider release.
+var distArtifactTypes = map[string]bool{"Archive": true, "Checksum": true, "Signature": true}
+
+// readDist reads the release from the artifacts.json and metadata.json in the goreleaser
+// dist directory.
+func readDist(directory string) (*distRelease, error) {
+	var metadata distMetadata
+	if err := readJsonFile(filepath.Join(directory, "metadata.json"), &metadata); err != nil {
+		return nil, err
+	}
+	if !strings.HasPrefix(metadata.ProjectName, "terraform-provider-") {
+		return nil, fmt.Errorf("project %s is not a terraform provider", metadata.ProjectName)
+	}
+	release := distRelease{
+		TypeName:  strings.TrimPrefix(metadata.ProjectName, "terraform-provider-"),
+		Version:   strings.TrimPrefix(metadata.Version, "v"),
+		directory: directory,
+	}
+
+	var artifacts []distArtifact
+	if err := readJsonFile(filepath.Join(directory, "artifacts.json"), &artifacts); err != nil {
+		return nil, err
+	}
+	manifest := fmt.Sprintf("%s_%s_manifest.json", metadata.ProjectName, release.Version)
+	for _, artifact := range artifacts {
+		if distArtifactTypes[artifact.Type] || artifact.Name == manifest {
+			release.Files = append(release.Files, artifact.Name)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(directory, manifest)); err == nil && !contains(release.Files, manifest) {
+		release.Files = append(release.Files, manifest)
+	}
+	for _, name := range release.Files {
+		if _, err := os.Stat(filepath.Join(directory, name)); err != nil {
+			return nil, fmt.Errorf("artifact %s not found in %s", name, directory)
+		}
+	}
+	return &release, nil
+}
+
+func readJsonFile(filename string, object interface{}) error {
+	content, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return fmt.Errorf("failed to read %s, %s", filename, err)
+	}
+	if err = json.Unmarshal(content, object); err != nil {
+		return fmt.Errorf("failed to unmarshal %s, %s", filename, err)
+	}
+	return nil
+}
+
+func contains(names []string, name string) bool {
+	for _, n := range names {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
+
+// check returns an error if the release has no archives, or an archive does not match the
+// naming scheme or the type and version of the release.
+func (r *distRelease) check(scheme *versions.NamingScheme) error {
+	archives := 0
+	for _, name := range r.Files {
+		release := scheme.ParseArchive(name)
+		if release == nil {
+			if !scheme.IsReleaseFile(name) && !strings.HasSuffix(name, "_manifest.json") {
+				return fmt.Errorf("%s does not match the naming scheme", name)
+			}
+			continue
+		}
+		if release.TypeName != r.TypeName || release.Version != r.Version {
+			return fmt.Errorf("%s is not an archive of %s %s", name, r.TypeName, r.Version)
+		}
+		archives++
+	}
+	if archives == 0 {
+		return fmt.Errorf("no provider archives found in %s", r.directory)
+	}
+	return nil
+}
+
+// contentTypeOf returns the content type of a release file.
+func contentTypeOf(name string) string {
+	switch {
+	case strings.HasSuffix(name, ".zip"):
+		return "application/zip"
+	case strings.HasSuffix(name, ".sig"):
+		return "application/pgp-signature"
+	case strings.HasSuffix(name, ".json"):
+		return "application/json"
+	}
+	return "text/plain"
+}
+
+// upload writes the files of the release to the directory in the bucket.
+func (r *distRelease) upload(bucket *Bucket, directory string, parallelism int) error {
+	tasks := make([]func() error, 0, len(r.Files))
+	for _, name := range r.Files {
+		name := name
+		tasks = append(tasks, func() error {
+			content, err := ioutil.ReadFile(filepath.Join(r.directory, name))
+			if err != nil {
+				return err
+			}
+			return writeObject(bucket, path.Join(directory, name), content, contentTypeOf(name))
+		})
+	}
+	return combineErrors(runParallel(parallelism, tasks))
+}
+
+// uploadDist uploads the release in the goreleaser dist directory to the release directory of
+// the provider, before its API documents are generated.
+func uploadDist(options *Options, provider *ProviderConfig) {
+	if err := versions.ValidateNamespace(provider.Namespace); err != nil {
+		fatalf(exitError, "%s", err)
+	}
+	release, err := readDist(options.Dist)
+	if err != nil {
+		fatalf(exitError, "%s", err)
+	}
+	if err = uploadRelease(options, provider, release); err != nil {
+		fatalf(exitCodeOf(err), "%s", err)
+	}
+}
+
+// uploadRelease checks the release and uploads it to the release directory of the provider.
+// An invalid release is returned as a validation error.
+func uploadRelease(options *Options, provider *ProviderConfig, release *distRelease) error {
+	if err := release.check(options.namingScheme); err != nil {
+		return validationError{err}
+	}
+
+	directory := strings.Trim(provider.Prefix, "/")
+	if provider.prefixTemplate != nil {
+		directory = provider.prefixTemplate.Directory(provider.Namespace, release.TypeName, release.Version)
+	}
+	log.WithFields(log.Fields{"type": release.TypeName, "version": release.Version, "directory": directory}).Info("uploading release")
+	return release.upload(options.bucket, directory, options.Parallelism)
+}
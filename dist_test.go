@@ -0,0 +1,68 @@
+package main
+
+import (
+	"github.com/mollie/tf-provider-registry-api-generator/versions"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestReadDist(t *testing.T) {
+	directory, err := ioutil.TempDir("", "dist")
+	if err != nil {
+		t.Fatalf("failed to create directory, %s", err)
+	}
+	defer os.RemoveAll(directory)
+
+	files := map[string]string{
+		"metadata.json": `{"project_name": "terraform-provider-mollie", "tag": "v1.2.3", "version": "1.2.3"}`,
+		"artifacts.json": `[
+  {"name": "terraform-provider-mollie_1.2.3_linux_amd64.zip", "path": "dist/terraform-provider-mollie_1.2.3_linux_amd64.zip", "goos": "linux", "goarch": "amd64", "type": "Archive"},
+  {"name": "terraform-provider-mollie_v1.2.3", "path": "dist/mollie_linux_amd64/terraform-provider-mollie_v1.2.3", "goos": "linux", "goarch": "amd64", "type": "Binary"},
+  {"name": "terraform-provider-mollie_1.2.3_SHA256SUMS", "path": "dist/terraform-provider-mollie_1.2.3_SHA256SUMS", "type": "Checksum"},
+  {"name": "terraform-provider-mollie_1.2.3_SHA256SUMS.sig", "path": "dist/terraform-provider-mollie_1.2.3_SHA256SUMS.sig", "type": "Signature"}
+]`,
+		"terraform-provider-mollie_1.2.3_linux_amd64.zip": "archive",
+		"terraform-provider-mollie_1.2.3_SHA256SUMS":      "shasums",
+		"terraform-provider-mollie_1.2.3_SHA256SUMS.sig":  "signature",
+		"terraform-provider-mollie_1.2.3_manifest.json":   `{"version": 1}`,
+	}
+	for name, content := range files {
+		if err = ioutil.WriteFile(filepath.Join(directory, name), []byte(content), 0644); err != nil {
+			t.Fatalf("failed to write %s, %s", name, err)
+		}
+	}
+
+	release, err := readDist(directory)
+	if err != nil {
+		t.Fatalf("failed to read dist, %s", err)
+	}
+	expect := []string{
+		"terraform-provider-mollie_1.2.3_linux_amd64.zip",
+		"terraform-provider-mollie_1.2.3_SHA256SUMS",
+		"terraform-provider-mollie_1.2.3_SHA256SUMS.sig",
+		"terraform-provider-mollie_1.2.3_manifest.json",
+	}
+	if release.TypeName != "mollie" || release.Version != "1.2.3" || !reflect.DeepEqual(release.Files, expect) {
+		t.Errorf("unexpected release %+v", release)
+	}
+	if err = release.check(versions.DefaultNamingScheme()); err != nil {
+		t.Errorf("unexpected error, %s", err)
+	}
+
+	release.Version = "1.2.4"
+	if err = release.check(versions.DefaultNamingScheme()); err == nil {
+		t.Errorf("expected an error for an archive of another version")
+	}
+	options := Options{namingScheme: versions.DefaultNamingScheme()}
+	if err = uploadRelease(&options, &ProviderConfig{Namespace: "mollie"}, release); exitCodeOf(err) != exitValidationFailed {
+		t.Errorf("expected a validation error for an invalid release, got %v", err)
+	}
+
+	os.Remove(filepath.Join(directory, "terraform-provider-mollie_1.2.3_SHA256SUMS.sig"))
+	if _, err = readDist(directory); err == nil {
+		t.Errorf("expected an error for a missing artifact")
+	}
+}
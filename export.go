@@ -0,0 +1,218 @@
+package main
+
+import (
+	"archive/zip"
+	"crypto/sha256"
+	"fmt"
+	"github.com/mollie/tf-provider-registry-api-generator/versions"
+	log "github.com/sirupsen/logrus"
+	"hash"
+	"io"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// export writes provider versions from the registry to a directory, in the packed or unpacked
+// layout of a Terraform filesystem mirror.
+type export struct {
+	bucket      *Bucket
+	registry    *RegistryConfig
+	config      *Config
+	scheme      *versions.NamingScheme
+	directory   string
+	hostname    string
+	unpacked    bool
+	allowed     versions.PlatformList
+	parallelism int
+}
+
+// packedPath returns the path of the archive of the platform in the packed layout.
+func (e *export) packedPath(namespace string, typeName string, version string, platform versions.Platform) string {
+	return filepath.Join(e.directory, e.hostname, namespace, typeName,
+		fmt.Sprintf("terraform-provider-%s_%s_%s.zip", typeName, version, platform))
+}
+
+// unpackedPath returns the directory of the platform in the unpacked layout.
+func (e *export) unpackedPath(namespace string, typeName string, version string, platform versions.Platform) string {
+	return filepath.Join(e.directory, e.hostname, namespace, typeName, version, platform.String())
+}
+
+// provider exports the newest version of the provider matching the constraint, for all
+// platforms in the allow-list.
+func (e *export) provider(namespace string, typeName string, constraint string) error {
+	providerPath := path.Join(e.registry.ProvidersPath(), namespace, typeName)
+	var providerVersions versions.ProviderVersions
+	if err := readJson(e.bucket, path.Join(providerPath, "versions"), &providerVersions); err != nil {
+		return err
+	}
+	if len(providerVersions.Versions) == 0 {
+		return fmt.Errorf("no versions of %s/%s found in the registry", namespace, typeName)
+	}
+	version, err := providerVersions.Resolve(constraint, nil)
+	if err != nil {
+		return fmt.Errorf("no version of %s/%s matches %s", namespace, typeName, constraint)
+	}
+
+	tasks := make([]func() error, 0, len(version.Platforms))
+	for _, platform := range version.Platforms {
+		if len(e.allowed) > 0 && !e.allowed.Contains(platform) {
+			continue
+		}
+		platform := platform
+		tasks = append(tasks, func() error {
+			download := path.Join(providerPath, version.Version, "download", platform.Os, platform.Arch)
+			var metadata versions.BinaryMetaData
+			if err := readJson(e.bucket, download, &metadata); err != nil {
+				return err
+			}
+			if metadata.DownloadURL == "" {
+				return fmt.Errorf("download document %s not found", download)
+			}
+			metadata.Namespace, metadata.TypeName, metadata.Version = namespace, typeName, version.Version
+			return e.binary(&metadata)
+		})
+	}
+	if len(tasks) == 0 {
+		return fmt.Errorf("%s/%s %s has none of the allowed platforms", namespace, typeName, version.Version)
+	}
+	return combineErrors(runParallel(e.parallelism, tasks))
+}
+
+// binary copies the archive of the binary to the directory, after verifying its shasum.
+func (e *export) binary(metadata *versions.BinaryMetaData) error {
+	object, ok := e.config.ReleaseFileOf(metadata, metadata.DownloadURL, e.scheme)
+	if !ok {
+		return fmt.Errorf("archive %s is not stored in the registry", metadata.DownloadURL)
+	}
+	packed := e.packedPath(metadata.Namespace, metadata.TypeName, metadata.Version, metadata.Platform())
+	if err := os.MkdirAll(filepath.Dir(packed), 0755); err != nil {
+		return err
+	}
+	file, err := ioutil.TempFile(filepath.Dir(packed), ".download-")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(file.Name())
+	defer file.Close()
+
+	var digest hash.Hash
+	err = e.bucket.ReadTo(object, func() io.Writer {
+		file.Truncate(0)
+		file.Seek(0, io.SeekStart)
+		digest = sha256.New()
+		return io.MultiWriter(file, digest)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to read file %s, %s", object, err)
+	}
+	if err = file.Close(); err != nil {
+		return err
+	}
+	if shasum := fmt.Sprintf("%x", digest.Sum(nil)); shasum != strings.ToLower(metadata.Shasum) {
+		return validationError{fmt.Errorf("the shasum of %s is %s, the download document says %s", object, shasum, metadata.Shasum)}
+	}
+
+	if !e.unpacked {
+		log.WithFields(metadata.LogFields()).WithField("path", packed).Info("exported provider")
+		return os.Rename(file.Name(), packed)
+	}
+	unpacked := e.unpackedPath(metadata.Namespace, metadata.TypeName, metadata.Version, metadata.Platform())
+	if err = os.RemoveAll(unpacked); err != nil {
+		return err
+	}
+	if err = extractArchive(file.Name(), unpacked); err != nil {
+		return fmt.Errorf("failed to extract %s, %s", object, err)
+	}
+	log.WithFields(metadata.LogFields()).WithField("path", unpacked).Info("exported provider")
+	return nil
+}
+
+// extractArchive extracts the zip archive into the directory. Entries outside the directory
+// are refused.
+func extractArchive(archive string, directory string) error {
+	r, err := zip.OpenReader(archive)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	for _, f := range r.File {
+		target := filepath.Join(directory, filepath.FromSlash(f.Name))
+		if target != directory && !strings.HasPrefix(target, filepath.Clean(directory)+string(filepath.Separator)) {
+			return fmt.Errorf("entry %s is outside of the archive", f.Name)
+		}
+		if f.FileInfo().IsDir() {
+			if err = os.MkdirAll(target, 0755); err != nil {
+				return err
+			}
+			continue
+		}
+		if err = extractFile(f, target); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func extractFile(f *zip.File, target string) error {
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return err
+	}
+	r, err := f.Open()
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	w, err := os.OpenFile(target, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, f.Mode().Perm()|0600)
+	if err != nil {
+		return err
+	}
+	if _, err = io.Copy(w, r); err != nil {
+		w.Close()
+		return err
+	}
+	return w.Close()
+}
+
+// exportProviders writes the providers from the registry to the directory as a filesystem
+// mirror, and exits.
+func exportProviders(options *Options, addresses []string) {
+	registry := &options.config.Registry
+	registryURL, err := url.Parse(registry.URL)
+	if err != nil || registryURL.Host == "" {
+		fatalf(exitError, "invalid url %s", registry.URL)
+	}
+	constraint := options.ProviderVersion
+	if constraint == "" {
+		constraint = ">= 0"
+	}
+	e := &export{
+		bucket:      options.bucket,
+		registry:    registry,
+		config:      options.config,
+		scheme:      options.namingScheme,
+		directory:   options.Directory,
+		hostname:    registryURL.Host,
+		unpacked:    options.Unpacked,
+		allowed:     options.allowedPlatforms,
+		parallelism: options.Parallelism,
+	}
+
+	for _, address := range addresses {
+		parts := strings.Split(address, "/")
+		if len(parts) != 2 || versions.ValidateNamespace(parts[0]) != nil || versions.ValidateTypeName(parts[1]) != nil {
+			fatalf(exitError, "%s is not a provider address of the form namespace/type", address)
+		}
+		if err = e.provider(parts[0], parts[1], constraint); err != nil {
+			fatalf(exitCodeOf(err), "failed to export %s, %s", address, err)
+		}
+	}
+	options.mutex.Close()
+	options.storage.Close()
+	report.Status = "exported"
+	report.Exit(exitPublished)
+}
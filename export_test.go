@@ -0,0 +1,69 @@
+package main
+
+import (
+	"archive/zip"
+	"github.com/mollie/tf-provider-registry-api-generator/versions"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeArchive(t *testing.T, filename string, entries map[string]string) {
+	f, err := os.Create(filename)
+	if err != nil {
+		t.Fatalf("failed to create %s, %s", filename, err)
+	}
+	defer f.Close()
+	w := zip.NewWriter(f)
+	for name, content := range entries {
+		header := &zip.FileHeader{Name: name, Method: zip.Deflate}
+		header.SetMode(0755)
+		entry, err := w.CreateHeader(header)
+		if err != nil {
+			t.Fatalf("failed to add %s, %s", name, err)
+		}
+		entry.Write([]byte(content))
+	}
+	if err = w.Close(); err != nil {
+		t.Fatalf("failed to write %s, %s", filename, err)
+	}
+}
+
+func TestExport_paths(t *testing.T) {
+	e := &export{directory: "mirror", hostname: "registry.example.com"}
+	platform := versions.Platform{Os: "linux", Arch: "amd64"}
+	if p := e.packedPath("mollie", "mollie", "1.0.0", platform); p != filepath.FromSlash("mirror/registry.example.com/mollie/mollie/terraform-provider-mollie_1.0.0_linux_amd64.zip") {
+		t.Errorf("unexpected packed path %s", p)
+	}
+	if p := e.unpackedPath("mollie", "mollie", "1.0.0", platform); p != filepath.FromSlash("mirror/registry.example.com/mollie/mollie/1.0.0/linux_amd64") {
+		t.Errorf("unexpected unpacked path %s", p)
+	}
+}
+
+func TestExtractArchive(t *testing.T) {
+	directory, err := ioutil.TempDir("", "export")
+	if err != nil {
+		t.Fatalf("failed to create directory, %s", err)
+	}
+	defer os.RemoveAll(directory)
+
+	archive := filepath.Join(directory, "provider.zip")
+	writeArchive(t, archive, map[string]string{"terraform-provider-mollie_v1.0.0": "binary"})
+	target := filepath.Join(directory, "linux_amd64")
+	if err = extractArchive(archive, target); err != nil {
+		t.Fatalf("failed to extract, %s", err)
+	}
+	info, err := os.Stat(filepath.Join(target, "terraform-provider-mollie_v1.0.0"))
+	if err != nil || info.Mode().Perm()&0100 == 0 {
+		t.Errorf("expected an executable provider, got %v, %v", info, err)
+	}
+
+	writeArchive(t, archive, map[string]string{"../escape": "binary"})
+	if err = extractArchive(archive, filepath.Join(directory, "other")); err == nil {
+		t.Errorf("expected an entry outside the directory to be refused")
+	}
+	if _, err = os.Stat(filepath.Join(directory, "escape")); err == nil {
+		t.Errorf("expected no file outside the directory")
+	}
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/mollie/tf-provider-registry-api-generator/versions"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// githubRelease is a release returned by the GitHub releases API.
+type githubRelease struct {
+	TagName string        `json:"tag_name"`
+	Assets  []githubAsset `json:"assets"`
+}
+
+// githubAsset is a file attached to a GitHub release.
+type githubAsset struct {
+	Name string `json:"name"`
+	URL  string `json:"url"`
+}
+
+// githubClient reads releases from GitHub or GitHub Enterprise.
+type githubClient struct {
+	httpClient *http.Client
+	baseURL    string
+	token      string
+}
+
+func (g *githubClient) get(ctx context.Context, location string, accept string) (*http.Response, error) {
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, location, nil)
+	if err != nil {
+		return nil, err
+	}
+	request.Header.Set("Accept", accept)
+	if g.token != "" {
+		request.Header.Set("Authorization", "token "+g.token)
+	}
+	response, err := g.httpClient.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	if response.StatusCode != http.StatusOK {
+		response.Body.Close()
+		return nil, fmt.Errorf("GET %s returned %s", location, response.Status)
+	}
+	return response, nil
+}
+
+// release returns the release of the repository with the tag.
+func (g *githubClient) release(ctx context.Context, repository string, tag string) (*githubRelease, error) {
+	location := fmt.Sprintf("%s/repos/%s/releases/tags/%s", strings.TrimRight(g.baseURL, "/"), repository, url.PathEscape(tag))
+	response, err := g.get(ctx, location, "application/vnd.github.v3+json")
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+	var result githubRelease
+	if err = json.NewDecoder(response.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("invalid response from %s, %s", location, err)
+	}
+	return &result, nil
+}
+
+// download writes the content of the asset to the file.
+func (g *githubClient) download(ctx context.Context, asset githubAsset, filename string) error {
+	response, err := g.get(ctx, asset.URL, "application/octet-stream")
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	f, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	if _, err = io.Copy(f, response.Body); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to download %s, %s", asset.Name, err)
+	}
+	return f.Close()
+}
+
+// downloadGithubRelease downloads the archives, SHA256SUMS, signature and manifest of the
+// release of the repository to the directory. Other assets are skipped.
+func downloadGithubRelease(ctx context.Context, g *githubClient, scheme *versions.NamingScheme, repository string, tag string, directory string, parallelism int) (*distRelease, error) {
+	name := path.Base(repository)
+	if strings.Count(repository, "/") != 1 || !strings.HasPrefix(name, "terraform-provider-") {
+		return nil, fmt.Errorf("repository %s is not of the form owner/terraform-provider-type", repository)
+	}
+	githubRelease, err := g.release(ctx, repository, tag)
+	if err != nil {
+		return nil, err
+	}
+
+	release := &distRelease{
+		TypeName:  strings.TrimPrefix(name, "terraform-provider-"),
+		Version:   strings.TrimPrefix(tag, "v"),
+		directory: directory,
+	}
+	tasks := make([]func() error, 0, len(githubRelease.Assets))
+	for _, asset := range githubRelease.Assets {
+		if !scheme.IsReleaseFile(asset.Name) && !strings.HasSuffix(asset.Name, "_manifest.json") {
+			continue
+		}
+		asset := asset
+		release.Files = append(release.Files, asset.Name)
+		tasks = append(tasks, func() error {
+			return g.download(ctx, asset, filepath.Join(directory, asset.Name))
+		})
+	}
+	if err = combineErrors(runParallel(parallelism, tasks)); err != nil {
+		return nil, err
+	}
+	return release, nil
+}
+
+// importGithubRelease downloads the release from GitHub, and uploads it to the release
+// directory of the provider, before its API documents are generated. The downloaded files
+// are removed before exiting.
+func importGithubRelease(options *Options, provider *ProviderConfig) {
+	if err := versions.ValidateNamespace(provider.Namespace); err != nil {
+		fatalf(exitError, "%s", err)
+	}
+	directory, err := ioutil.TempDir("", "tf-registry-github-")
+	if err != nil {
+		fatalf(exitError, "%s", err)
+	}
+
+	g := &githubClient{
+		httpClient: newHTTPClient(options.httpTimeout),
+		baseURL:    options.GithubApi,
+		token:      os.Getenv("GITHUB_TOKEN"),
+	}
+	release, err := downloadGithubRelease(context.Background(), g, options.namingScheme, options.Repo, options.Tag, directory, options.Parallelism)
+	if err != nil {
+		os.RemoveAll(directory)
+		fatalf(exitError, "failed to download release %s of %s, %s", options.Tag, options.Repo, err)
+	}
+	err = uploadRelease(options, provider, release)
+	os.RemoveAll(directory)
+	if err != nil {
+		fatalf(exitCodeOf(err), "%s", err)
+	}
+}
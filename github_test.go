@@ -0,0 +1,78 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"github.com/mollie/tf-provider-registry-api-generator/versions"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDownloadGithubRelease(t *testing.T) {
+	assets := map[string]string{
+		"terraform-provider-mollie_1.2.3_linux_amd64.zip": "archive",
+		"terraform-provider-mollie_1.2.3_SHA256SUMS":      "shasums",
+		"terraform-provider-mollie_1.2.3_SHA256SUMS.sig":  "signature",
+		"README.md": "readme",
+	}
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "token secret" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		if r.URL.Path == "/api/v3/repos/mollie/terraform-provider-mollie/releases/tags/v1.2.3" {
+			fmt.Fprint(w, `{"tag_name": "v1.2.3", "assets": [`)
+			i := 0
+			for name := range assets {
+				if i > 0 {
+					fmt.Fprint(w, ",")
+				}
+				fmt.Fprintf(w, `{"name": %q, "url": "%s/api/v3/assets/%s"}`, name, server.URL, name)
+				i++
+			}
+			fmt.Fprint(w, `]}`)
+			return
+		}
+		if content, ok := assets[filepath.Base(r.URL.Path)]; ok && r.Header.Get("Accept") == "application/octet-stream" {
+			fmt.Fprint(w, content)
+			return
+		}
+		http.NotFound(w, r)
+	}))
+	defer server.Close()
+
+	directory, err := ioutil.TempDir("", "github")
+	if err != nil {
+		t.Fatalf("failed to create directory, %s", err)
+	}
+	defer os.RemoveAll(directory)
+
+	g := &githubClient{httpClient: server.Client(), baseURL: server.URL + "/api/v3/", token: "secret"}
+	scheme := versions.DefaultNamingScheme()
+	release, err := downloadGithubRelease(context.Background(), g, scheme, "mollie/terraform-provider-mollie", "v1.2.3", directory, 2)
+	if err != nil {
+		t.Fatalf("failed to download release, %s", err)
+	}
+	if release.TypeName != "mollie" || release.Version != "1.2.3" || len(release.Files) != 3 {
+		t.Errorf("unexpected release %+v", release)
+	}
+	if err = release.check(scheme); err != nil {
+		t.Errorf("unexpected error, %s", err)
+	}
+	content, err := ioutil.ReadFile(filepath.Join(directory, "terraform-provider-mollie_1.2.3_SHA256SUMS.sig"))
+	if err != nil || string(content) != "signature" {
+		t.Errorf("expected the signature to be downloaded, got %q, %v", content, err)
+	}
+
+	if _, err = downloadGithubRelease(context.Background(), g, scheme, "mollie/terraform-provider-mollie", "v9.9.9", directory, 2); err == nil {
+		t.Errorf("expected an error for an unknown tag")
+	}
+	if _, err = downloadGithubRelease(context.Background(), g, scheme, "mollie/mollie", "v1.2.3", directory, 2); err == nil {
+		t.Errorf("expected an error for a repository which is not a provider")
+	}
+}
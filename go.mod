@@ -1,12 +1,17 @@
 module github.com/mollie/tf-provider-registry-api-generator
 
-go 1.15
+go 1.16
 
 require (
 	cloud.google.com/go/storage v1.14.0
 	github.com/alexflint/go-filemutex v1.1.0
 	github.com/binxio/gcloudconfig v0.1.5
 	github.com/docopt/docopt-go v0.0.0-20180111231733-ee0de3bc6815
+	github.com/hashicorp/go-version v1.3.0
+	github.com/sirupsen/logrus v1.8.1
+	github.com/xeipuuv/gojsonschema v1.2.0
+	golang.org/x/crypto v0.0.0-20210220033148-5ea612d1eb83
 	golang.org/x/oauth2 v0.0.0-20210220000619-9bb904979d93
 	google.golang.org/api v0.40.0
+	gopkg.in/yaml.v2 v2.4.0
 )
@@ -0,0 +1,140 @@
+// Package registrytest serves a provider registry with signed releases, for the tests of the
+// registry client and of the commands which read from an upstream registry.
+package registrytest
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"github.com/mollie/tf-provider-registry-api-generator/signing_key"
+	"github.com/mollie/tf-provider-registry-api-generator/versions"
+	"golang.org/x/crypto/openpgp"
+	"golang.org/x/crypto/openpgp/armor"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// Release is a provider version served by the registry. The archive of a platform contains
+// "archive of <version> for <platform>".
+type Release struct {
+	Namespace string
+	TypeName  string
+	Version   string
+	Platforms []versions.Platform
+	// Tampered are the platforms of which the archive does not match the signed SHA256SUMS.
+	Tampered []versions.Platform
+	// Missing are the platforms listed in the versions document without a download document.
+	Missing []versions.Platform
+}
+
+// Directory returns the path of the release files of the release.
+func (r *Release) Directory() string {
+	return fmt.Sprintf("/binaries/%s/terraform-provider-%s/v%s/", r.Namespace, r.TypeName, r.Version)
+}
+
+// Registry is a provider registry serving the releases signed by its signing key.
+type Registry struct {
+	*httptest.Server
+	SigningKey *signing_key.PrivateSigningKey
+	PublicKey  signing_key.PGPSigningKey
+}
+
+// NewSigningKey generates a private signing key.
+func NewSigningKey(t testing.TB, name string) *signing_key.PrivateSigningKey {
+	t.Helper()
+	entity, err := openpgp.NewEntity(name, "", name+"@example.com", nil)
+	if err != nil {
+		t.Fatalf("failed to generate key, %s", err)
+	}
+	var armored bytes.Buffer
+	w, _ := armor.Encode(&armored, openpgp.PrivateKeyType, nil)
+	if err = entity.SerializePrivate(w, nil); err != nil {
+		t.Fatalf("failed to serialize key, %s", err)
+	}
+	w.Close()
+	key, err := signing_key.ReadPrivateSigningKey(armored.String(), "")
+	if err != nil {
+		t.Fatalf("failed to read key, %s", err)
+	}
+	return key
+}
+
+// New starts a registry serving the releases, signed by a new key with the source name. The
+// server is closed at the end of the test.
+func New(t testing.TB, source string, releases ...Release) *Registry {
+	t.Helper()
+	privateKey := NewSigningKey(t, source)
+	publicKey, err := privateKey.PublicKey()
+	if err != nil {
+		t.Fatalf("failed to export public key, %s", err)
+	}
+	publicKey.Source = source
+
+	scheme := versions.DefaultNamingScheme()
+	files := map[string][]byte{
+		"/.well-known/terraform.json": []byte(`{"providers.v1": "/v1/providers/"}`),
+	}
+	providers := make(map[string]*versions.ProviderVersions)
+	for _, release := range releases {
+		provider := release.Namespace + "/" + release.TypeName
+		if providers[provider] == nil {
+			providers[provider] = &versions.ProviderVersions{}
+		}
+		providers[provider].AddProviderVersion(versions.ProviderVersion{
+			Version:   release.Version,
+			Protocols: []string{"5.0"},
+			Platforms: append(append([]versions.Platform{}, release.Platforms...), release.Missing...),
+		})
+
+		directory := release.Directory()
+		shasumsFile := scheme.ShasumsFileName(release.TypeName, release.Version)
+		signatureFile := scheme.SignatureFileName(release.TypeName, release.Version)
+		var shasums bytes.Buffer
+		for _, platform := range release.Platforms {
+			filename := fmt.Sprintf("terraform-provider-%s_%s_%s.zip", release.TypeName, release.Version, platform)
+			archive := []byte(fmt.Sprintf("archive of %s for %s", release.Version, platform))
+			digest := sha256.Sum256(archive)
+			fmt.Fprintf(&shasums, "%s  %s\n", hex.EncodeToString(digest[:]), filename)
+			if versions.PlatformList(release.Tampered).Contains(platform) {
+				archive = []byte("tampered")
+			}
+
+			metadata := versions.BinaryMetaData{
+				Protocols:           []string{"5.0"},
+				Os:                  platform.Os,
+				Arch:                platform.Arch,
+				Filename:            filename,
+				DownloadURL:         directory + filename,
+				ShasumsURL:          directory + shasumsFile,
+				ShasumsSignatureURL: directory + signatureFile,
+				Shasum:              hex.EncodeToString(digest[:]),
+			}
+			metadata.SetPGPSigningKeys([]signing_key.PGPSigningKey{publicKey})
+			files[fmt.Sprintf("/v1/providers/%s/%s/download/%s/%s", provider, release.Version, platform.Os, platform.Arch)], _ = json.Marshal(metadata)
+			files[directory+filename] = archive
+		}
+		signature, err := privateKey.Sign(shasums.Bytes())
+		if err != nil {
+			t.Fatalf("failed to sign, %s", err)
+		}
+		files[directory+shasumsFile] = shasums.Bytes()
+		files[directory+signatureFile] = signature
+	}
+	for provider, providerVersions := range providers {
+		files["/v1/providers/"+provider+"/versions"], _ = json.Marshal(providerVersions)
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		content, ok := files[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write(content)
+	}))
+	t.Cleanup(server.Close)
+	return &Registry{Server: server, SigningKey: privateKey, PublicKey: publicKey}
+}
@@ -11,10 +11,15 @@ import (
 	"github.com/docopt/docopt-go"
 	"golang.org/x/oauth2/google"
 	"google.golang.org/api/option"
-	"log"
+	"net"
+	"net/http"
+	log "github.com/sirupsen/logrus"
 	"os"
 	"regexp"
+	"os/signal"
 	"strings"
+	"syscall"
+	"time"
 )
 
 type Options struct {
@@ -22,17 +27,73 @@ type Options struct {
 	Namespace             string
 	Url                   string
 	Prefix                string
+	PrefixTemplate        string
+	Config                string
 	Fingerprint           string
 	Protocols             string
+	KeySource             string
+	KeySourceUrl          string
+	TrustSignature        string
+	Sign                  bool
+	GenerateShasums       bool
+	ArchiveTemplate       string
+	ShasumsTemplate       string
+	SignatureTemplate     string
+	PlatformMapping       string
+	RequiredPlatforms     string
+	AllowedPlatforms      string
+	AllowIncomplete       bool
+	SigningKey            string
+	PassphraseFile        string
+	Parallelism           int
+	Timeout               string
+	HttpTimeout           string
+	Retries               int
+	Output                string
+	LogLevel              string
+	LogFormat             string
 	UseDefaultCredentials bool
 	Help                  bool
-	Version               bool
+	Version               bool `docopt:"version"`
+	Audit                 bool
+	Fix                   bool
+	Validate              bool
+	Document              []string
+	Check                 bool
+	Host                  string
+	Provider              string
+	ProviderVersion       string `docopt:"--version"`
+	Mirror                bool
+	Upstream              string
+	Address               []string
+	Export                bool
+	Unpacked              bool
+	Directory             string
+	Publish               bool
+	Dist                  string
+	ArtifactBase          string
+	ArtifactUrl           string
+	RewriteUrls           bool `docopt:"rewrite-urls"`
+	From                  string
+	To                    string
+	DryRun                bool
+	ImportGithub          bool `docopt:"import-github"`
+	Repo                  string
+	Tag                   string
+	GithubApi             string
 	storage               *storage.Client
-	bucket                *storage.BucketHandle
+	bucket                *Bucket
 	credentials           *google.Credentials
 	mutexFileName         string
 	mutex                 *filemutex.FileMutex
 	protocols             []string
+	config                *Config
+	privateSigningKey     *signing_key.PrivateSigningKey
+	namingScheme          *versions.NamingScheme
+	timeout               time.Duration
+	httpTimeout           time.Duration
+	requiredPlatforms     versions.PlatformList
+	allowedPlatforms      versions.PlatformList
 }
 
 var (
@@ -45,30 +106,86 @@ var (
 
 func main() {
 	var options Options
+	log.AddHook(report)
+	log.StandardLogger().ExitFunc = report.Exit
 	usage := `generate terraform provider registry API documents.
 
 Usage:
-  tf-provider-registry-api-generator [--use-default-credentials] [--fingerprint FINGERPRINT]  --bucket-name BUCKET --url URL --namespace NAMESPACE [--protocols PROTOCOLS ] --prefix PREFIX
+  tf-provider-registry-api-generator [options] --bucket-name BUCKET --url URL --namespace NAMESPACE --prefix PREFIX
+  tf-provider-registry-api-generator [options] --bucket-name BUCKET --url URL [--namespace NAMESPACE] --prefix-template TEMPLATE
+  tf-provider-registry-api-generator [options] --config FILE
+  tf-provider-registry-api-generator publish [options] --dist DIR --bucket-name BUCKET --url URL --namespace NAMESPACE --prefix PREFIX
+  tf-provider-registry-api-generator publish [options] --dist DIR --bucket-name BUCKET --url URL --namespace NAMESPACE --prefix-template TEMPLATE
+  tf-provider-registry-api-generator import-github [options] [--github-api URL] --repo REPO --tag TAG --bucket-name BUCKET --url URL --namespace NAMESPACE --prefix PREFIX
+  tf-provider-registry-api-generator import-github [options] [--github-api URL] --repo REPO --tag TAG --bucket-name BUCKET --url URL --namespace NAMESPACE --prefix-template TEMPLATE
+  tf-provider-registry-api-generator audit [options] [--fix] --bucket-name BUCKET --url URL
+  tf-provider-registry-api-generator audit [options] [--fix] --config FILE
+  tf-provider-registry-api-generator rewrite-urls [options] [--dry-run] --from URL --to URL --bucket-name BUCKET --url URL
+  tf-provider-registry-api-generator rewrite-urls [options] [--dry-run] --from URL --to URL --config FILE
+  tf-provider-registry-api-generator validate [options] <document>...
+  tf-provider-registry-api-generator check [options] --host HOST --provider PROVIDER [--version VERSION]
+  tf-provider-registry-api-generator mirror [options] [--version VERSION] --upstream HOST --bucket-name BUCKET --url URL --prefix-template TEMPLATE <address>...
+  tf-provider-registry-api-generator export [options] [--unpacked] [--version VERSION] --bucket-name BUCKET --url URL --directory DIR <address>...
+  tf-provider-registry-api-generator export [options] [--unpacked] [--version VERSION] --config FILE --directory DIR <address>...
   tf-provider-registry-api-generator version
   tf-provider-registry-api-generator -h | --help
 
 Options:
-  --bucket-name BUCKET       - bucket containing the binaries and the website.
-  --url URL                  - of the static website.
-  --namespace NAMESPACE      - for the providers.
-  --prefix PREFIX            - location of the released binaries in the bucket.
-  --protocols PROTOCOL       - comma separated list of supported provider protocols by the provider [default: 5.0]
-  --fingerprint FINGERPRINT  - of the public key used to sign, defaults to environment variable GPG_FINGERPRINT.
-  --use-default-credentials  - instead of the current gcloud configuration.
-  -h --help                  - shows this.
+  --bucket-name BUCKET           - bucket containing the binaries and the website.
+  --url URL                      - of the static website.
+  --namespace NAMESPACE          - for the providers.
+  --prefix PREFIX                - location of the released binaries in the bucket.
+  --prefix-template TEMPLATE     - of the release directories in the bucket, with the placeholders {namespace}, {type} and {version}.
+  --config FILE                  - YAML or JSON file describing the registry and the providers to publish.
+  --dist DIR                     - goreleaser dist directory of the release to upload before publishing.
+  --artifact-base URL            - base url of the release files, if not served from the url of the registry.
+  --artifact-url TEMPLATE        - of the release files, with the placeholders {artifactBase}, {path}, {namespace}, {type}, {version} and {filename} [default: {artifactBase}/{path}]
+  --repo REPO                    - GitHub repository of the release to import, as owner/terraform-provider-type.
+  --tag TAG                      - of the GitHub release to import.
+  --github-api URL               - of the GitHub or GitHub Enterprise API. The token is read from GITHUB_TOKEN [default: https://api.github.com]
+  --protocols PROTOCOL           - comma separated list of supported provider protocols by the provider [default: 5.0]
+  --archive-template TEMPLATE    - of the provider archive file names [default: terraform-provider-{type}_{version}_{os}_{arch}.zip]
+  --shasums-template TEMPLATE    - of the SHA256SUMS file names [default: terraform-provider-{type}_{version}_SHA256SUMS]
+  --signature-template TEMPLATE  - of the SHA256SUMS signature file names [default: terraform-provider-{type}_{version}_SHA256SUMS.sig]
+  --platform-mapping MAPPING     - comma separated list of name=os or name=arch, mapping release file names to terraform platforms.
+  --required-platforms PLATFORMS - comma separated list of os_arch which must be available for each version.
+  --allow-incomplete             - warn instead of fail if a version misses a required platform.
+  --allowed-platforms PLATFORMS  - comma separated list of os_arch which may be published.
+  --fingerprint FINGERPRINT      - comma separated list of public keys used to sign, defaults to environment variable GPG_FINGERPRINT.
+  --key-source SOURCE            - name of the organization which owns the signing keys.
+  --key-source-url URL           - where the signing keys of the source are published.
+  --trust-signature FILE         - containing the ASCII armored trust signature of the signing key.
+  --generate-shasums             - for releases without a SHA256SUMS file.
+  --sign                         - the SHA256SUMS of releases without a signature.
+  --signing-key FILE             - containing the ASCII armored private key to sign with, defaults to environment variable GPG_SIGNING_KEY.
+  --passphrase-file FILE         - containing the passphrase of the private key, defaults to environment variable GPG_SIGNING_KEY_PASSPHRASE.
+  --parallelism N                - maximum number of concurrent storage requests [default: 4]
+  --timeout DURATION             - of each storage request [default: 30s]
+  --http-timeout DURATION        - to connect and receive the response headers of requests to registries and GitHub [default: 30s]
+  --retries N                    - of storage requests failing with a transient error [default: 5]
+  --output FORMAT                - of the result, text or json. json writes a report to stdout [default: text]
+  --log-level LEVEL              - minimum level of the log messages, debug, info, warn or error [default: info]
+  --log-format FORMAT            - of the log messages on stderr, text or json [default: text]
+  --use-default-credentials      - instead of the current gcloud configuration.
+  --fix                          - the inconsistencies found by the audit.
+  --from URL                     - base url to replace in the urls of all download documents.
+  --to URL                       - base url to replace it with.
+  --dry-run                      - report the download documents which would be rewritten, without writing them.
+  --host HOST                    - of the registry to check, like registry.example.com.
+  --provider PROVIDER            - to check, as namespace/type.
+  --version VERSION              - exact version or version constraint to check, mirror or export. The newest matching version is used, or the newest version if not specified.
+  --upstream HOST                - registry to mirror the providers from, like registry.terraform.io.
+  --directory DIR                - to export the providers to, as a terraform filesystem mirror.
+  --unpacked                     - export the providers in the unpacked layout, instead of as zip archives.
+  -h --help                      - shows this.
 `
 
 	arguments, err := docopt.ParseDoc(usage)
 	if err != nil {
-		log.Fatalf("ERROR: failed to parse command line, %s", err)
+		fatalf(exitError, "failed to parse command line, %s", err)
 	}
 	if err = arguments.Bind(&options); err != nil {
-		log.Fatalf("ERROR: failed to bind arguments from command line, %s", err)
+		fatalf(exitError, "failed to bind arguments from command line, %s", err)
 	}
 
 	if options.Version {
@@ -76,75 +193,304 @@ Options:
 		os.Exit(0)
 	}
 
+	if err = configureLogging(options.LogLevel, options.LogFormat); err != nil {
+		fatalf(exitError, "%s", err)
+	}
+
+	switch options.Output {
+	case "text":
+	case "json":
+		report.json = true
+	default:
+		fatalf(exitError, "unsupported output format %s, use text or json", options.Output)
+	}
+
+	if options.Parallelism < 1 {
+		fatalf(exitError, "parallelism must be at least 1")
+	}
+	if options.timeout, err = time.ParseDuration(options.Timeout); err != nil || options.timeout <= 0 {
+		fatalf(exitError, "invalid timeout %s", options.Timeout)
+	}
+	if options.httpTimeout, err = time.ParseDuration(options.HttpTimeout); err != nil || options.httpTimeout <= 0 {
+		fatalf(exitError, "invalid http timeout %s", options.HttpTimeout)
+	}
+	if options.Retries < 0 {
+		fatalf(exitError, "retries must not be negative")
+	}
+
+	if options.Validate {
+		validateDocuments(options.Document, options.httpTimeout)
+	}
+	if options.Check {
+		checkProvider(options.Host, options.Provider, options.ProviderVersion, options.httpTimeout, options.Parallelism)
+	}
+
 	options.protocols = make([]string, 0)
 	for _, p := range strings.Split(options.Protocols, ",") {
 		if !protocolRegex.Match([]byte(p)) {
-			log.Fatalf("ERROR: %s is not a version number", p)
+			fatalf(exitError, "%s is not a version number", p)
 		}
 		options.protocols = append(options.protocols, p)
 	}
 	if len(options.protocols) == 0 {
-		log.Fatalf("ERROR: no protocols specified")
+		fatalf(exitError, "no protocols specified")
+	}
+
+	if options.Config != "" {
+		if err = checkConfigOptions(&options); err != nil {
+			fatalf(exitError, "%s", err)
+		}
+		if options.config, err = LoadConfig(options.Config); err != nil {
+			fatalf(exitError, "%s", err)
+		}
+	} else if options.config, err = configFromOptions(&options); err != nil {
+		fatalf(exitError, "%s", err)
+	}
+	if options.Audit || options.Export || options.RewriteUrls {
+		err = options.config.ValidateRegistry()
+	} else {
+		err = options.config.Validate(options.protocols)
+	}
+	if err != nil {
+		fatalf(exitError, "%s", err)
+	}
+
+	platformMapping := make(map[string]string)
+	for _, m := range strings.Split(options.PlatformMapping, ",") {
+		if m = strings.TrimSpace(m); m == "" {
+			continue
+		}
+		parts := strings.SplitN(m, "=", 2)
+		if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+			fatalf(exitError, "%s is not a platform mapping of the form name=os or name=arch", m)
+		}
+		platformMapping[parts[0]] = parts[1]
+	}
+	options.namingScheme, err = versions.NewNamingScheme(options.ArchiveTemplate, options.ShasumsTemplate, options.SignatureTemplate, platformMapping)
+	if err != nil {
+		fatalf(exitError, "%s", err)
+	}
+
+	if options.requiredPlatforms, err = versions.ParsePlatformList(options.RequiredPlatforms); err != nil {
+		fatalf(exitError, "invalid required platforms, %s", err)
+	}
+	if options.allowedPlatforms, err = versions.ParsePlatformList(options.AllowedPlatforms); err != nil {
+		fatalf(exitError, "invalid allowed platforms, %s", err)
+	}
+
+	if options.Audit {
+		openBucket(&options)
+		auditRegistry(&options)
+	}
+	if options.RewriteUrls {
+		openBucket(&options)
+		rewriteURLs(&options)
+	}
+
+	if options.Export {
+		openBucket(&options)
+		exportProviders(&options, options.Address)
 	}
 
-	if options.Fingerprint == "" {
-		options.Fingerprint = os.Getenv("GPG_FINGERPRINT")
-		if options.Fingerprint == "" {
-			log.Fatalf("ERROR: no fingerprint specified")
+	if options.Mirror {
+		openBucket(&options)
+		mirrorProviders(&options, options.Address)
+		closeBucketAndExit(&options)
+	}
+
+	if options.Sign {
+		options.privateSigningKey = loadPrivateSigningKey(options.SigningKey, options.PassphraseFile)
+	}
+	if len(options.config.Registry.SigningKeys) == 0 && options.Config != "" {
+		config, err := configFromOptions(&options)
+		if err != nil {
+			fatalf(exitError, "%s", err)
 		}
+		options.config.Registry.SigningKeys = config.Registry.SigningKeys
 	}
-	options.mutexFileName = fmt.Sprintf("/tmp/tf-registry-generator-%s.lck", options.BucketName)
+	if len(options.config.Registry.SigningKeys) == 0 && !options.Sign {
+		fatalf(exitError, "no fingerprint specified")
+	}
+	openBucket(&options)
+
+	signingKeys, err := options.config.Registry.LoadSigningKeys()
+	if err != nil {
+		fatalf(exitError, "%s", err)
+	}
+	if options.privateSigningKey != nil {
+		signingKeys = addPublicSigningKey(signingKeys, options.privateSigningKey)
+	}
+
+	if options.Dist != "" {
+		uploadDist(&options, &options.config.Providers[0])
+	}
+	if options.ImportGithub {
+		importGithubRelease(&options, &options.config.Providers[0])
+	}
+	for i := range options.config.Providers {
+		publishProvider(&options, &options.config.Providers[i], signingKeys)
+	}
+	closeBucketAndExit(&options)
+}
+
+// closeBucketAndExit releases the lock and the storage client, and exits with nothing-to-do
+// if none of the documents changed.
+func closeBucketAndExit(options *Options) {
+	options.mutex.Close()
+	options.storage.Close()
+
+	if !report.Changed() {
+		log.Infof("nothing to do, all documents are up-to-date")
+		report.Exit(exitNothingToDo)
+	}
+	report.Exit(exitPublished)
+}
+
+// openBucket creates the storage client with the gcloud or default credentials, and obtains
+// the lock on the bucket.
+func openBucket(options *Options) {
+	var err error
+	options.mutexFileName = fmt.Sprintf("/tmp/tf-registry-generator-%s.lck", options.config.Registry.Bucket)
 
 	if options.UseDefaultCredentials || !gcloudconfig.IsGCloudOnPath() {
-		log.Printf("INFO: using default credentials")
+		log.Infof("using default credentials")
 		if options.credentials, err = google.FindDefaultCredentials(context.Background(), "https://www.googleapis.com/auth/devstorage.full_control"); err != nil {
-			log.Fatalf("ERROR: failed to get default credentials, %s", err)
+			fatalf(exitError, "failed to get default credentials, %s", err)
 		}
 	} else {
 		if options.credentials, err = gcloudconfig.GetCredentials(""); err != nil {
-			log.Fatalf("ERROR: failed to get gcloud config credentials, %s", err)
+			fatalf(exitError, "failed to get gcloud config credentials, %s", err)
 		}
 	}
 
 	options.storage, err = storage.NewClient(context.Background(), option.WithCredentials(options.credentials))
 	if err != nil {
-		log.Fatalf("ERROR: could not create storage client, %s", err)
+		fatalf(exitError, "could not create storage client, %s", err)
 	}
-	defer options.storage.Close()
 
-	options.bucket = options.storage.Bucket(options.BucketName)
+	options.bucket = NewBucket(cancelOnSignal(), options.storage, options.config.Registry.Bucket, options.timeout, options.Retries)
 	options.mutex, err = filemutex.New(options.mutexFileName)
 	if err != nil {
-		log.Fatalf("ERROR: failed to create lock file %s, %s", options.mutexFileName, err)
+		fatalf(exitError, "failed to create lock file %s, %s", options.mutexFileName, err)
 	}
-	defer options.mutex.Close()
 
 	err = options.mutex.Lock()
 	if err != nil {
-		log.Fatalf("ERROR: failed to obtain lock, %s", err)
+		fatalf(exitError, "failed to obtain lock, %s", err)
 	}
+}
 
-	signingKey := signing_key.GetPublicSigningKey(options.Fingerprint)
-	files := versions.LoadFromBucket(options.bucket, options.Prefix)
-	if len(files) == 0 {
-		log.Fatalf("ERROR: no release files found in %s at %s", options.BucketName, options.Prefix)
+// configureLogging sets the minimum level and the format of the log messages.
+func configureLogging(level string, format string) error {
+	logLevel, err := log.ParseLevel(level)
+	if err != nil {
+		return err
 	}
+	log.SetLevel(logLevel)
 
-	shasums := make(map[string]string, len(files))
-	for _, filename := range files {
-		if strings.HasSuffix(filename, "SHA256SUMS") {
-			err = readShasums(options.bucket, filename, shasums)
-			if err != nil {
-				log.Fatalf("%s", err)
-			}
+	switch format {
+	case "text":
+		log.SetFormatter(&log.TextFormatter{FullTimestamp: true})
+	case "json":
+		log.SetFormatter(&log.JSONFormatter{})
+	default:
+		return fmt.Errorf("unsupported log format %s, use text or json", format)
+	}
+	return nil
+}
+
+// checkConfigOptions returns an error if the registry or a provider is specified on the command
+// line together with a configuration file, as they are only read from the file. The signing
+// keys on the command line are used if the file specifies none.
+func checkConfigOptions(options *Options) error {
+	flags := []struct {
+		name  string
+		value string
+	}{
+		{"--bucket-name", options.BucketName},
+		{"--url", options.Url},
+		{"--namespace", options.Namespace},
+		{"--prefix", options.Prefix},
+		{"--prefix-template", options.PrefixTemplate},
+		{"--artifact-base", options.ArtifactBase},
+	}
+	for _, flag := range flags {
+		if flag.value != "" {
+			return fmt.Errorf("%s cannot be combined with --config, specify it in %s instead", flag.name, options.Config)
 		}
 	}
+	return nil
+}
 
-	binaries := versions.CreateFromFileList(files, options.Url, signingKey, shasums, options.protocols)
-	providers := binaries.ExtractVersions()
-	if len(providers) == 0 {
-		log.Fatalf("ERROR: no terraform provider binaries detected")
+// configFromOptions creates the configuration of a single provider from the command line.
+// The fingerprints default to the environment variable GPG_FINGERPRINT. A trust signature
+// signs a single key, so it cannot be specified for more than one fingerprint.
+func configFromOptions(options *Options) (*Config, error) {
+	fingerprint := options.Fingerprint
+	if fingerprint == "" {
+		fingerprint = os.Getenv("GPG_FINGERPRINT")
 	}
+	fingerprints := make([]string, 0)
+	for _, f := range strings.Split(fingerprint, ",") {
+		if f = strings.TrimSpace(f); f != "" {
+			fingerprints = append(fingerprints, f)
+		}
+	}
+	if options.TrustSignature != "" && len(fingerprints) > 1 {
+		return nil, fmt.Errorf("--trust-signature applies to a single fingerprint, specify the trust_signature_file of each signing key in the --config file instead")
+	}
+
+	config := Config{
+		Registry: RegistryConfig{
+			Bucket:              options.BucketName,
+			URL:                 options.Url,
+			ArtifactBase:        options.ArtifactBase,
+			ArtifactURLTemplate: options.ArtifactUrl,
+			SigningKeys:         make([]SigningKeyConfig, 0),
+		},
+		Providers: []ProviderConfig{{
+			Namespace:      options.Namespace,
+			Prefix:         options.Prefix,
+			PrefixTemplate: options.PrefixTemplate,
+			Protocols:      options.protocols,
+		}},
+	}
+	for _, f := range fingerprints {
+		config.Registry.SigningKeys = append(config.Registry.SigningKeys, SigningKeyConfig{
+			Fingerprint:        f,
+			Source:             options.KeySource,
+			SourceURL:          options.KeySourceUrl,
+			TrustSignatureFile: options.TrustSignature,
+		})
+	}
+	return &config, nil
+}
+
+// newHTTPClient returns a client for registries and GitHub. The timeout applies to connecting
+// and to receiving the response headers, not to reading the body, so that large provider
+// archives can be downloaded.
+func newHTTPClient(timeout time.Duration) *http.Client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialContext = (&net.Dialer{Timeout: timeout, KeepAlive: 30 * time.Second}).DialContext
+	transport.TLSHandshakeTimeout = timeout
+	transport.ResponseHeaderTimeout = timeout
+	return &http.Client{Transport: transport}
+}
 
-	WriteAPIDocuments(options.bucket, options.Namespace, binaries)
+// cancelOnSignal returns a context which is cancelled on SIGINT or SIGTERM, so that no new
+// storage operations are started while in-flight operations are completed. A second signal
+// terminates the process immediately.
+func cancelOnSignal() context.Context {
+	ctx, cancel := context.WithCancel(context.Background())
+	signals := make(chan os.Signal, 2)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+	go func() {
+		sig := <-signals
+		log.WithField("signal", sig.String()).Warn("completing in-flight requests before terminating")
+		cancel()
+		sig = <-signals
+		log.WithField("signal", sig.String()).Errorf("terminating")
+		report.Exit(exitError)
+	}()
+	return ctx
 }
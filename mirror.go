@@ -0,0 +1,246 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"github.com/mollie/tf-provider-registry-api-generator/client"
+	"github.com/mollie/tf-provider-registry-api-generator/versions"
+	log "github.com/sirupsen/logrus"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// mirror copies provider versions from an upstream registry into the release layout of the
+// bucket. The archives, SHA256SUMS and signatures are verified before they are stored, and
+// the download documents keep the signing keys of the upstream registry.
+type mirror struct {
+	upstream    *client.Client
+	options     *versions.ReleaseOptions
+	allowed     versions.PlatformList
+	parallelism int
+	store       func(filename string, content []byte, contentType string) error
+	// shasumOf returns the SHA-256 of the object in the bucket, or false if it does not exist.
+	shasumOf func(filename string) (string, bool, error)
+}
+
+// mirroredPlatform is the package of a platform, stored in the bucket. The archive is not
+// kept, as the archives of all platforms of large providers do not fit in memory.
+type mirroredPlatform struct {
+	filename  string
+	metadata  *versions.BinaryMetaData
+	shasums   []byte
+	signature []byte
+}
+
+// release copies the newest version of the provider matching the constraint, for all
+// platforms in the allow-list, and returns the binary metadata of the copies.
+func (m *mirror) release(ctx context.Context, namespace string, typeName string, constraint string) (versions.BinaryMetaDataList, error) {
+	version, err := m.upstream.ResolveVersion(ctx, namespace, typeName, constraint, nil)
+	if err != nil {
+		return nil, err
+	}
+	platforms := make(versions.PlatformList, 0, len(version.Platforms))
+	for _, platform := range version.Platforms {
+		if len(m.allowed) == 0 || m.allowed.Contains(platform) {
+			platforms = append(platforms, platform)
+		}
+	}
+	if len(platforms) == 0 {
+		return nil, fmt.Errorf("%s/%s %s has none of the allowed platforms", namespace, typeName, version.Version)
+	}
+	sort.Sort(platforms)
+
+	directory := m.options.PrefixTemplate.Directory(namespace, typeName, version.Version)
+	log.WithFields(log.Fields{"namespace": namespace, "type": typeName, "version": version.Version, "directory": directory}).Info("mirroring provider")
+
+	var mutex sync.Mutex
+	invalid := false
+	results := make([]mirroredPlatform, len(platforms))
+	tasks := make([]func() error, 0, len(platforms))
+	for i, platform := range platforms {
+		i, platform := i, platform
+		tasks = append(tasks, func() error {
+			metadata, err := m.upstream.Download(ctx, namespace, typeName, version.Version, platform)
+			if err != nil {
+				return err
+			}
+			pkg, err := m.upstream.Fetch(ctx, metadata)
+			if err != nil {
+				return err
+			}
+			filename := path.Join(directory, metadata.Filename)
+			if err = pkg.Verify(); err == nil {
+				err = m.checkArchive(filename, typeName, version.Version, platform)
+			}
+			if err != nil {
+				mutex.Lock()
+				invalid = true
+				mutex.Unlock()
+				return fmt.Errorf("%s/%s %s %s, %s", namespace, typeName, version.Version, platform, err)
+			}
+			if err = m.put(filename, pkg.Archive, "application/zip"); err != nil {
+				if errors.As(err, &validationError{}) {
+					mutex.Lock()
+					invalid = true
+					mutex.Unlock()
+				}
+				return err
+			}
+			results[i] = mirroredPlatform{filename: filename, metadata: pkg.Metadata, shasums: pkg.Shasums, signature: pkg.Signature}
+			return nil
+		})
+	}
+	if err = combineErrors(runParallel(m.parallelism, tasks)); err != nil {
+		if invalid {
+			return nil, validationError{err}
+		}
+		return nil, err
+	}
+
+	first := results[0]
+	for _, result := range results[1:] {
+		if !bytes.Equal(result.shasums, first.shasums) {
+			return nil, validationError{fmt.Errorf("the platforms of %s/%s %s have different SHA256SUMS", namespace, typeName, version.Version)}
+		}
+	}
+	shasums := make(map[string]string)
+	if err = versions.ParseShasumsOf(bytes.NewReader(first.shasums), directory, shasums); err != nil {
+		return nil, validationError{fmt.Errorf("invalid SHA256SUMS of %s/%s %s, %s", namespace, typeName, version.Version, err)}
+	}
+	if err = m.put(path.Join(directory, m.options.Scheme.ShasumsFileName(typeName, version.Version)), first.shasums, "text/plain"); err != nil {
+		return nil, err
+	}
+	if err = m.put(path.Join(directory, m.options.Scheme.SignatureFileName(typeName, version.Version)), first.signature, "application/pgp-signature"); err != nil {
+		return nil, err
+	}
+
+	binaries := make(versions.BinaryMetaDataList, 0, len(results))
+	for _, result := range results {
+		metadata, err := versions.MakeFromFileName(m.options, result.filename, shasums)
+		if err != nil {
+			return nil, validationError{err}
+		}
+		metadata.Protocols = result.metadata.Protocols
+		metadata.SigningKeys = result.metadata.SigningKeys
+		binaries = append(binaries, *metadata)
+	}
+	return binaries, nil
+}
+
+// put stores the content, unless the object already exists with the same content. An
+// existing object with other content is not overwritten, as Terraform clients may have
+// recorded its checksum, and is returned as a validation error.
+func (m *mirror) put(filename string, content []byte, contentType string) error {
+	shasum, exists, err := m.shasumOf(filename)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return m.store(filename, content, contentType)
+	}
+	if shasum != fmt.Sprintf("%x", sha256.Sum256(content)) {
+		return validationError{fmt.Errorf("%s already exists with content other than the upstream release", filename)}
+	}
+	log.WithField("path", filename).Debug("skipping, object already mirrored")
+	return nil
+}
+
+// checkArchive returns an error if the name of the archive does not match the naming scheme,
+// or differs in type, version or platform from the upstream release.
+func (m *mirror) checkArchive(filename string, typeName string, version string, platform versions.Platform) error {
+	release := m.options.Parse(filename)
+	if release == nil {
+		return fmt.Errorf("%s does not match the archive template %s", path.Base(filename), m.options.Scheme.ArchiveTemplate)
+	}
+	if release.TypeName != typeName || release.Version != version || release.Os != platform.Os || release.Arch != platform.Arch {
+		return fmt.Errorf("%s is not the archive of %s %s %s", path.Base(filename), typeName, version, platform)
+	}
+	return nil
+}
+
+// mirrorProviders copies the providers from the upstream registry into the bucket, and
+// publishes their API documents.
+func mirrorProviders(options *Options, addresses []string) {
+	registry := &options.config.Registry
+	provider := &options.config.Providers[0]
+	if !provider.prefixTemplate.HasNamespace() {
+		fatalf(exitError, "the prefix template %s must contain {namespace} to mirror providers", provider.PrefixTemplate)
+	}
+	constraint := options.ProviderVersion
+	if constraint == "" {
+		constraint = ">= 0"
+	}
+
+	upstream, err := client.New(options.Upstream, newHTTPClient(options.httpTimeout))
+	if err != nil {
+		fatalf(exitError, "%s", err)
+	}
+	var mutex sync.Mutex
+	storageFailed := false
+	failed := func(err error) error {
+		if err != nil {
+			mutex.Lock()
+			storageFailed = true
+			mutex.Unlock()
+		}
+		return err
+	}
+	m := &mirror{
+		upstream: upstream,
+		options: &versions.ReleaseOptions{
+			Scheme:         options.namingScheme,
+			PrefixTemplate: provider.prefixTemplate,
+			BaseURL:        registry.URL,
+			ArtifactURL:    registry.artifactURL,
+			Protocols:      provider.Protocols,
+		},
+		allowed:     options.allowedPlatforms,
+		parallelism: options.Parallelism,
+		store: func(filename string, content []byte, contentType string) error {
+			return failed(writeObject(options.bucket, filename, content, contentType))
+		},
+		shasumOf: func(filename string) (string, bool, error) {
+			exists, err := options.bucket.Exists(filename)
+			if err != nil || !exists {
+				return "", false, failed(err)
+			}
+			shasum, err := computeShasum(options.bucket, filename)
+			return shasum, true, failed(err)
+		},
+	}
+
+	binaries := make(versions.BinaryMetaDataList, 0)
+	for _, address := range addresses {
+		parts := strings.Split(address, "/")
+		if len(parts) != 2 || versions.ValidateNamespace(parts[0]) != nil || versions.ValidateTypeName(parts[1]) != nil {
+			fatalf(exitError, "%s is not a provider address of the form namespace/type", address)
+		}
+		release, err := m.release(context.Background(), parts[0], parts[1], constraint)
+		if err != nil {
+			var invalid validationError
+			switch {
+			case errors.As(err, &invalid):
+				fatalf(exitValidationFailed, "%s", err)
+			case storageFailed:
+				fatalf(exitStorageError, "%s", err)
+			default:
+				fatalf(exitError, "failed to mirror %s from %s, %s", address, options.Upstream, err)
+			}
+		}
+		binaries = append(binaries, release...)
+	}
+
+	providers := binaries.ExtractVersions()
+	if err = checkRequiredPlatforms(providers, options.requiredPlatforms, options.AllowIncomplete); err != nil {
+		fatalf(exitCodeOf(err), "%s", err)
+	}
+	if err = WriteAPIDocuments(options.bucket, registry, binaries, options.Parallelism); err != nil {
+		fatalf(exitCodeOf(err), "%s", err)
+	}
+	report.Published(providers)
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/mollie/tf-provider-registry-api-generator/client"
+	"github.com/mollie/tf-provider-registry-api-generator/internal/registrytest"
+	"github.com/mollie/tf-provider-registry-api-generator/versions"
+	"testing"
+)
+
+// newUpstream returns a registry serving hashicorp/google 4.0.0 for darwin_amd64 and
+// linux_amd64. If tampered, the linux archive does not match its shasum.
+func newUpstream(t *testing.T, tampered bool) *registrytest.Registry {
+	release := registrytest.Release{
+		Namespace: "hashicorp",
+		TypeName:  "google",
+		Version:   "4.0.0",
+		Platforms: []versions.Platform{{Os: "linux", Arch: "amd64"}, {Os: "darwin", Arch: "amd64"}},
+	}
+	if tampered {
+		release.Tampered = []versions.Platform{{Os: "linux", Arch: "amd64"}}
+	}
+	return registrytest.New(t, "HashiCorp", release)
+}
+
+func newTestMirror(t *testing.T, server *registrytest.Registry, stored map[string][]byte) *mirror {
+	upstream, err := client.New(server.URL, server.Client())
+	if err != nil {
+		t.Fatalf("failed to create client, %s", err)
+	}
+	template, _ := versions.NewPrefixTemplate("mirror/{namespace}/{type}/{version}")
+	return &mirror{
+		upstream: upstream,
+		options: &versions.ReleaseOptions{
+			Scheme:         versions.DefaultNamingScheme(),
+			PrefixTemplate: template,
+			BaseURL:        "https://registry.example.com",
+			Protocols:      []string{"5.0"},
+		},
+		parallelism: 2,
+		store: func(filename string, content []byte, contentType string) error {
+			stored[filename] = content
+			return nil
+		},
+		shasumOf: func(filename string) (string, bool, error) {
+			content, ok := stored[filename]
+			return fmt.Sprintf("%x", sha256.Sum256(content)), ok, nil
+		},
+	}
+}
+
+func TestMirror(t *testing.T) {
+	server := newUpstream(t, false)
+	stored := make(map[string][]byte)
+	m := newTestMirror(t, server, stored)
+	m.allowed = versions.PlatformList{{Os: "linux", Arch: "amd64"}}
+
+	binaries, err := m.release(context.Background(), "hashicorp", "google", "~> 4.0")
+	if err != nil {
+		t.Fatalf("failed to mirror, %s", err)
+	}
+	for _, name := range []string{
+		"mirror/hashicorp/google/4.0.0/terraform-provider-google_4.0.0_linux_amd64.zip",
+		"mirror/hashicorp/google/4.0.0/terraform-provider-google_4.0.0_SHA256SUMS",
+		"mirror/hashicorp/google/4.0.0/terraform-provider-google_4.0.0_SHA256SUMS.sig",
+	} {
+		if _, ok := stored[name]; !ok {
+			t.Errorf("expected %s to be stored", name)
+		}
+	}
+	if len(stored) != 3 {
+		t.Errorf("expected only the linux_amd64 release to be stored, got %d objects", len(stored))
+	}
+
+	if len(binaries) != 1 {
+		t.Fatalf("expected 1 binary, got %d", len(binaries))
+	}
+	binary := binaries[0]
+	if binary.Namespace != "hashicorp" || binary.DownloadURL != "https://registry.example.com/mirror/hashicorp/google/4.0.0/terraform-provider-google_4.0.0_linux_amd64.zip" {
+		t.Errorf("unexpected binary %+v", binary)
+	}
+	keys := binary.SigningKeys.GpgPublicKeys
+	if len(keys) != 1 || keys[0].Source != "HashiCorp" {
+		t.Errorf("expected the upstream signing key, got %+v", keys)
+	}
+	document, _ := json.Marshal(binary)
+	if err = versions.ValidateDocument(versions.DownloadDocument, document); err != nil {
+		t.Errorf("invalid download document, %s", err)
+	}
+}
+
+func TestMirror_tampered(t *testing.T) {
+	server := newUpstream(t, true)
+	stored := make(map[string][]byte)
+	m := newTestMirror(t, server, stored)
+
+	_, err := m.release(context.Background(), "hashicorp", "google", ">= 0")
+	var invalid validationError
+	if !errors.As(err, &invalid) {
+		t.Fatalf("expected a validation error, got %v", err)
+	}
+	if _, ok := stored["mirror/hashicorp/google/4.0.0/terraform-provider-google_4.0.0_linux_amd64.zip"]; ok {
+		t.Errorf("expected the tampered archive not to be stored")
+	}
+}
+
+func TestMirror_existing(t *testing.T) {
+	server := newUpstream(t, false)
+	archive := "mirror/hashicorp/google/4.0.0/terraform-provider-google_4.0.0_linux_amd64.zip"
+	stored := make(map[string][]byte)
+	m := newTestMirror(t, server, stored)
+	m.allowed = versions.PlatformList{{Os: "linux", Arch: "amd64"}}
+	if _, err := m.release(context.Background(), "hashicorp", "google", "4.0.0"); err != nil {
+		t.Fatalf("failed to mirror, %s", err)
+	}
+
+	writes := 0
+	store := m.store
+	m.store = func(filename string, content []byte, contentType string) error {
+		writes++
+		return store(filename, content, contentType)
+	}
+	if _, err := m.release(context.Background(), "hashicorp", "google", "4.0.0"); err != nil || writes != 0 {
+		t.Errorf("expected the mirrored objects to be skipped, got %d writes, %v", writes, err)
+	}
+
+	stored[archive] = []byte("other content")
+	_, err := m.release(context.Background(), "hashicorp", "google", "4.0.0")
+	var invalid validationError
+	if !errors.As(err, &invalid) {
+		t.Fatalf("expected a validation error, got %v", err)
+	}
+	if string(stored[archive]) != "other content" || writes != 0 {
+		t.Errorf("expected the existing archive not to be overwritten")
+	}
+}
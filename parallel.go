@@ -0,0 +1,51 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// runParallel runs the tasks with at most parallelism tasks at the same time, and waits
+// for all of them to complete. It returns the errors of all failed tasks.
+func runParallel(parallelism int, tasks []func() error) []error {
+	if parallelism < 1 {
+		parallelism = 1
+	}
+
+	var mutex sync.Mutex
+	var wg sync.WaitGroup
+	errors := make([]error, 0)
+	semaphore := make(chan struct{}, parallelism)
+
+	for _, task := range tasks {
+		wg.Add(1)
+		semaphore <- struct{}{}
+		go func(task func() error) {
+			defer wg.Done()
+			defer func() { <-semaphore }()
+			if err := task(); err != nil {
+				mutex.Lock()
+				errors = append(errors, err)
+				mutex.Unlock()
+			}
+		}(task)
+	}
+	wg.Wait()
+	return errors
+}
+
+// combineErrors returns a single error reporting all errors, or nil if there are none.
+func combineErrors(errors []error) error {
+	if len(errors) == 0 {
+		return nil
+	}
+	if len(errors) == 1 {
+		return errors[0]
+	}
+	messages := make([]string, 0, len(errors))
+	for _, err := range errors {
+		messages = append(messages, err.Error())
+	}
+	return fmt.Errorf("%d errors occurred:\n  %s", len(errors), strings.Join(messages, "\n  "))
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"fmt"
+	"sync/atomic"
+	"testing"
+)
+
+func TestRunParallel(t *testing.T) {
+	var running, maxRunning int32
+	tasks := make([]func() error, 0)
+	for i := 0; i < 20; i++ {
+		i := i
+		tasks = append(tasks, func() error {
+			n := atomic.AddInt32(&running, 1)
+			defer atomic.AddInt32(&running, -1)
+			for {
+				m := atomic.LoadInt32(&maxRunning)
+				if n <= m || atomic.CompareAndSwapInt32(&maxRunning, m, n) {
+					break
+				}
+			}
+			if i%5 == 0 {
+				return fmt.Errorf("task %d failed", i)
+			}
+			return nil
+		})
+	}
+
+	errors := runParallel(3, tasks)
+	if len(errors) != 4 {
+		t.Errorf("expected 4 errors, got %d", len(errors))
+	}
+	if maxRunning > 3 {
+		t.Errorf("expected at most 3 concurrent tasks, got %d", maxRunning)
+	}
+	if combineErrors(errors) == nil {
+		t.Errorf("expected a combined error")
+	}
+	if combineErrors(nil) != nil {
+		t.Errorf("expected no error")
+	}
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"fmt"
+	"github.com/mollie/tf-provider-registry-api-generator/signing_key"
+	"github.com/mollie/tf-provider-registry-api-generator/versions"
+	log "github.com/sirupsen/logrus"
+	"strings"
+)
+
+// publishProvider generates the API documents of the provider releases found at the prefix
+// of the provider.
+func publishProvider(options *Options, provider *ProviderConfig, signingKeys []signing_key.PGPSigningKey) {
+	registry := &options.config.Registry
+	releaseOptions := &versions.ReleaseOptions{
+		Scheme:         options.namingScheme,
+		PrefixTemplate: provider.prefixTemplate,
+		Namespace:      provider.Namespace,
+		BaseURL:        registry.URL,
+		ArtifactURL:    registry.artifactURL,
+		Protocols:      provider.Protocols,
+	}
+	log.WithField("prefix", provider.ListPrefix()).Info("publishing providers")
+
+	names, err := options.bucket.List(provider.ListPrefix())
+	if err != nil {
+		fatalf(exitStorageError, "failed to list objects from bucket, %s", err)
+	}
+	files := versions.SelectReleaseFiles(names, releaseOptions)
+	if len(files) == 0 {
+		fatalf(exitError, "no release files found in %s at %s", registry.Bucket, provider.ListPrefix())
+	}
+	if err = versions.ValidateReleaseFiles(releaseOptions, files); err != nil {
+		fatalf(exitValidationFailed, "%s", err)
+	}
+
+	shasums := make(map[string]string, len(files))
+	signatures := make(map[string][]byte)
+	for _, filename := range files {
+		if options.namingScheme.ParseShasums(filename) != nil {
+			err = readShasums(options.bucket, filename, shasums)
+			if err != nil {
+				fatalf(exitCodeOf(err), "%s", err)
+			}
+		}
+		if options.namingScheme.ParseSignature(filename) != nil {
+			err = readSignature(options.bucket, filename, signatures)
+			if err != nil {
+				fatalf(exitStorageError, "%s", err)
+			}
+		}
+	}
+
+	if options.GenerateShasums {
+		files, err = generateShasums(options.namingScheme, files, shasums, options.Parallelism,
+			func(filename string) (string, error) { return computeShasum(options.bucket, filename) },
+			func(filename string, content []byte) error {
+				return writeObject(options.bucket, filename, content, "text/plain")
+			})
+		if err != nil {
+			fatalf(exitStorageError, "%s", err)
+		}
+	}
+	if options.privateSigningKey != nil {
+		files = signShasums(options.bucket, options.namingScheme, files, signatures, options.privateSigningKey)
+	}
+
+	binaries, err := versions.CreateFromFileList(releaseOptions, files, signingKeys, signatures, shasums)
+	if err != nil {
+		fatalf(exitValidationFailed, "%s", err)
+	}
+	providers := binaries.ExtractVersions()
+	if len(providers) == 0 {
+		fatalf(exitError, "no terraform provider binaries detected")
+	}
+	if err = binaries.ValidatePlatforms(options.allowedPlatforms); err != nil {
+		fatalf(exitValidationFailed, "%s", err)
+	}
+	if err = checkRequiredPlatforms(providers, options.requiredPlatforms, options.AllowIncomplete); err != nil {
+		fatalf(exitCodeOf(err), "%s", err)
+	}
+
+	if err = WriteAPIDocuments(options.bucket, registry, binaries, options.Parallelism); err != nil {
+		fatalf(exitCodeOf(err), "%s", err)
+	}
+	report.Published(providers)
+}
+
+// providerFields returns the fields identifying the provider namespace/type in log messages.
+func providerFields(name string) log.Fields {
+	parts := strings.SplitN(name, "/", 2)
+	if len(parts) != 2 {
+		return log.Fields{"provider": name}
+	}
+	return log.Fields{"namespace": parts[0], "type": parts[1]}
+}
+
+// checkRequiredPlatforms returns a validation error if a version misses a required platform,
+// or warns if incomplete versions are allowed.
+func checkRequiredPlatforms(providers map[string]*versions.ProviderVersions, required versions.PlatformList, allowIncomplete bool) error {
+	incomplete := false
+	for name, providerVersions := range providers {
+		for _, version := range providerVersions.Versions {
+			missing := version.MissingPlatforms(required)
+			if len(missing) == 0 {
+				continue
+			}
+			incomplete = true
+			names := make([]string, 0, len(missing))
+			for _, platform := range missing {
+				names = append(names, platform.String())
+			}
+			entry := log.WithFields(providerFields(name)).WithFields(log.Fields{
+				"version":           version.Version,
+				"missing_platforms": strings.Join(names, ","),
+			})
+			if allowIncomplete {
+				entry.Warn("version is missing required platforms")
+			} else {
+				entry.Error("version is missing required platforms")
+			}
+		}
+	}
+	if incomplete && !allowIncomplete {
+		return validationError{fmt.Errorf("not all required platforms are available")}
+	}
+	return nil
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"github.com/mollie/tf-provider-registry-api-generator/versions"
+	"testing"
+)
+
+func TestCheckRequiredPlatforms(t *testing.T) {
+	required := versions.PlatformList{{Os: "darwin", Arch: "amd64"}, {Os: "linux", Arch: "amd64"}}
+	tests := []struct {
+		name            string
+		platforms       []versions.Platform
+		allowIncomplete bool
+		wantErr         bool
+	}{
+		{"complete", []versions.Platform{{Os: "darwin", Arch: "amd64"}, {Os: "linux", Arch: "amd64"}, {Os: "linux", Arch: "arm64"}}, false, false},
+		{"incomplete", []versions.Platform{{Os: "linux", Arch: "amd64"}}, false, true},
+		{"incomplete_allowed", []versions.Platform{{Os: "linux", Arch: "amd64"}}, true, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			providers := map[string]*versions.ProviderVersions{
+				"mollie/mollie": {Versions: []versions.ProviderVersion{
+					{Version: "1.0.0", Protocols: []string{"5.0"}, Platforms: required},
+					{Version: "1.1.0", Protocols: []string{"5.0"}, Platforms: tt.platforms},
+				}},
+			}
+			err := checkRequiredPlatforms(providers, required, tt.allowIncomplete)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("expected error %v, got %v", tt.wantErr, err)
+			}
+			if err != nil && exitCodeOf(err) != exitValidationFailed {
+				t.Errorf("expected a validation error, got %v", err)
+			}
+		})
+	}
+	if err := checkRequiredPlatforms(map[string]*versions.ProviderVersions{}, nil, false); err != nil {
+		t.Errorf("expected no error without required platforms, got %v", err)
+	}
+}
@@ -0,0 +1,195 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/mollie/tf-provider-registry-api-generator/versions"
+	log "github.com/sirupsen/logrus"
+	"os"
+	"sort"
+	"sync"
+)
+
+// The exit codes of the generator.
+const (
+	exitPublished        = 0
+	exitError            = 1
+	exitValidationFailed = 2
+	exitStorageError     = 3
+	exitNothingToDo      = 4
+)
+
+var exitStatus = map[int]string{
+	exitPublished:        "published",
+	exitError:            "error",
+	exitValidationFailed: "validation-failed",
+	exitStorageError:     "storage-error",
+	exitNothingToDo:      "nothing-to-do",
+}
+
+// PublishedProvider lists the versions of a provider which were published.
+type PublishedProvider struct {
+	Name     string   `json:"name"`
+	Versions []string `json:"versions"`
+}
+
+// LogEntry is a warning or error which was logged.
+type LogEntry struct {
+	Message string                 `json:"message"`
+	Fields  map[string]interface{} `json:"fields,omitempty"`
+}
+
+// Report collects the result of a run. It is a hook of the logger, which collects the logged
+// warnings and errors.
+type Report struct {
+	Status    string              `json:"status"`
+	ExitCode  int                 `json:"exit_code"`
+	Created   []string            `json:"created"`
+	Updated   []string            `json:"updated"`
+	Unchanged []string            `json:"unchanged"`
+	Providers []PublishedProvider `json:"providers"`
+	Warnings  []LogEntry          `json:"warnings"`
+	Errors    []LogEntry          `json:"errors"`
+	Findings  []*Finding          `json:"findings,omitempty"`
+	Checks    []*CheckResult      `json:"checks,omitempty"`
+	json      bool
+	documents map[string]bool
+	mutex     sync.Mutex
+}
+
+// report is the result of this run.
+var report = NewReport()
+
+// NewReport returns an empty report.
+func NewReport() *Report {
+	return &Report{
+		Created:   make([]string, 0),
+		Updated:   make([]string, 0),
+		Unchanged: make([]string, 0),
+		Providers: make([]PublishedProvider, 0),
+		Warnings:  make([]LogEntry, 0),
+		Errors:    make([]LogEntry, 0),
+		documents: make(map[string]bool),
+	}
+}
+
+// Levels returns the levels of the log entries recorded by the report.
+func (r *Report) Levels() []log.Level {
+	return []log.Level{log.PanicLevel, log.FatalLevel, log.ErrorLevel, log.WarnLevel}
+}
+
+// Fire records the warning or error.
+func (r *Report) Fire(entry *log.Entry) error {
+	logEntry := LogEntry{Message: entry.Message}
+	if len(entry.Data) > 0 {
+		logEntry.Fields = make(map[string]interface{}, len(entry.Data))
+		for name, value := range entry.Data {
+			if err, ok := value.(error); ok {
+				value = err.Error()
+			}
+			logEntry.Fields[name] = value
+		}
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if entry.Level == log.WarnLevel {
+		r.Warnings = append(r.Warnings, logEntry)
+	} else {
+		r.Errors = append(r.Errors, logEntry)
+	}
+	return nil
+}
+
+// Document records whether the document was created, updated or unchanged. Only the first
+// outcome of a document is recorded.
+func (r *Report) Document(name string, existed bool, changed bool) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if r.documents[name] {
+		return
+	}
+	r.documents[name] = true
+	switch {
+	case !changed:
+		r.Unchanged = append(r.Unchanged, name)
+	case existed:
+		r.Updated = append(r.Updated, name)
+	default:
+		r.Created = append(r.Created, name)
+	}
+}
+
+// Published records the provider versions which were published.
+func (r *Report) Published(providers map[string]*versions.ProviderVersions) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	for name, providerVersions := range providers {
+		sorted := append(versions.ProviderVersionList(nil), providerVersions.Versions...)
+		sort.Sort(sorted)
+		published := PublishedProvider{Name: name, Versions: make([]string, 0, len(sorted))}
+		for _, version := range sorted {
+			published.Versions = append(published.Versions, version.Version)
+		}
+		r.Providers = append(r.Providers, published)
+	}
+	sort.Slice(r.Providers, func(i, j int) bool { return r.Providers[i].Name < r.Providers[j].Name })
+}
+
+// Changed returns true if any document was created or updated.
+func (r *Report) Changed() bool {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return len(r.Created) > 0 || len(r.Updated) > 0
+}
+
+// Exit writes the report as JSON to stdout if requested, and exits with the exit code. The
+// status defaults to the status of the exit code.
+func (r *Report) Exit(exitCode int) {
+	r.mutex.Lock()
+	r.ExitCode = exitCode
+	if r.Status == "" {
+		r.Status = exitStatus[exitCode]
+	}
+	if r.json {
+		sort.Strings(r.Created)
+		sort.Strings(r.Updated)
+		sort.Strings(r.Unchanged)
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(r); err != nil {
+			fmt.Fprintf(os.Stderr, "failed to write the report, %s\n", err)
+		}
+	}
+	r.mutex.Unlock()
+	os.Exit(exitCode)
+}
+
+// fatalf logs the error and exits with the exit code.
+func fatalf(exitCode int, format string, args ...interface{}) {
+	log.Errorf(format, args...)
+	report.Exit(exitCode)
+}
+
+// validationError is an error of a release or document which violates the registry protocol.
+type validationError struct {
+	err error
+}
+
+func (e validationError) Error() string {
+	return e.err.Error()
+}
+
+func (e validationError) Unwrap() error {
+	return e.err
+}
+
+// exitCodeOf returns the exit code for an error returned by the storage or publication.
+func exitCodeOf(err error) int {
+	var invalid validationError
+	if errors.As(err, &invalid) {
+		return exitValidationFailed
+	}
+	return exitStorageError
+}
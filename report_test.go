@@ -0,0 +1,74 @@
+package main
+
+import (
+	"fmt"
+	"github.com/mollie/tf-provider-registry-api-generator/versions"
+	log "github.com/sirupsen/logrus"
+	"io/ioutil"
+	"reflect"
+	"testing"
+)
+
+func TestReport(t *testing.T) {
+	r := NewReport()
+	logger := log.New()
+	logger.Out = ioutil.Discard
+	logger.AddHook(r)
+	logger.WithField("path", "v1/providers/mollie/mollie/versions").Info("writing document")
+	logger.WithField("filename", "a.sig").Warn("no signature found")
+	logger.WithError(fmt.Errorf("timeout")).Error("failed")
+
+	expectedWarnings := []LogEntry{{Message: "no signature found", Fields: map[string]interface{}{"filename": "a.sig"}}}
+	if !reflect.DeepEqual(r.Warnings, expectedWarnings) {
+		t.Errorf("expected %v to be recorded, got %v", expectedWarnings, r.Warnings)
+	}
+	expectedErrors := []LogEntry{{Message: "failed", Fields: map[string]interface{}{"error": "timeout"}}}
+	if !reflect.DeepEqual(r.Errors, expectedErrors) {
+		t.Errorf("expected %v to be recorded, got %v", expectedErrors, r.Errors)
+	}
+
+	if r.Changed() {
+		t.Errorf("expected an empty report to be unchanged")
+	}
+	r.Document("a", false, true)
+	r.Document("b", true, true)
+	r.Document("c", true, false)
+	r.Document("a", true, false)
+	if !reflect.DeepEqual(r.Created, []string{"a"}) || !reflect.DeepEqual(r.Updated, []string{"b"}) || !reflect.DeepEqual(r.Unchanged, []string{"c"}) {
+		t.Errorf("unexpected documents, created %v, updated %v, unchanged %v", r.Created, r.Updated, r.Unchanged)
+	}
+	if !r.Changed() {
+		t.Errorf("expected the report to be changed")
+	}
+}
+
+func TestReport_Published(t *testing.T) {
+	r := NewReport()
+	providers := map[string]*versions.ProviderVersions{
+		"mollie/mollie": {Versions: []versions.ProviderVersion{{Version: "1.10.0"}, {Version: "1.9.0"}, {Version: "1.10.0-beta1"}}},
+	}
+	r.Published(providers)
+	expected := []PublishedProvider{{Name: "mollie/mollie", Versions: []string{"1.9.0", "1.10.0-beta1", "1.10.0"}}}
+	if !reflect.DeepEqual(r.Providers, expected) {
+		t.Errorf("expected %v, got %v", expected, r.Providers)
+	}
+	if providers["mollie/mollie"].Versions[0].Version != "1.10.0" {
+		t.Errorf("expected the versions of the provider to be left unchanged")
+	}
+}
+
+func TestExitCodeOf(t *testing.T) {
+	tests := []struct {
+		err      error
+		exitCode int
+	}{
+		{fmt.Errorf("failed to write a"), exitStorageError},
+		{validationError{fmt.Errorf("invalid shasum")}, exitValidationFailed},
+		{fmt.Errorf("wrapped, %w", validationError{fmt.Errorf("invalid")}), exitValidationFailed},
+	}
+	for _, tt := range tests {
+		if got := exitCodeOf(tt.err); got != tt.exitCode {
+			t.Errorf("exitCodeOf(%s) = %d, expected %d", tt.err, got, tt.exitCode)
+		}
+	}
+}
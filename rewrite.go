@@ -0,0 +1,100 @@
+package main
+
+import (
+	"fmt"
+	"github.com/mollie/tf-provider-registry-api-generator/versions"
+	log "github.com/sirupsen/logrus"
+	"net/url"
+	"strings"
+)
+
+// rewriteURL returns the url with the base url from replaced by to, or false if the url is not
+// below from.
+func rewriteURL(location string, from string, to string) (string, bool) {
+	if location != from && !strings.HasPrefix(location, from+"/") {
+		return location, false
+	}
+	return to + strings.TrimPrefix(location, from), true
+}
+
+// rewriteDocument replaces the base url from by to in all urls of the download document, and
+// returns true if any of them changed.
+func rewriteDocument(download *versions.BinaryMetaData, from string, to string) bool {
+	references := []*string{&download.DownloadURL, &download.ShasumsURL, &download.ShasumsSignatureURL}
+	for _, key := range download.SigningKeys.GpgPublicKeys {
+		if key.SourceURL != nil {
+			references = append(references, key.SourceURL)
+		}
+	}
+
+	changed := false
+	for _, reference := range references {
+		if rewritten, ok := rewriteURL(*reference, from, to); ok && rewritten != *reference {
+			*reference = rewritten
+			changed = true
+		}
+	}
+	return changed
+}
+
+func validateBaseURL(location string) (string, error) {
+	parsed, err := url.Parse(location)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return "", fmt.Errorf("%s is not an http or https url", location)
+	}
+	return strings.TrimRight(location, "/"), nil
+}
+
+// rewriteURLs replaces the base url in all download documents of the registry, and exits. In a
+// dry-run, the documents which would change are only reported.
+func rewriteURLs(options *Options) {
+	from, err := validateBaseURL(options.From)
+	if err != nil {
+		fatalf(exitError, "%s", err)
+	}
+	to, err := validateBaseURL(options.To)
+	if err != nil {
+		fatalf(exitError, "%s", err)
+	}
+
+	registry := &options.config.Registry
+	a := newAudit(options.bucket, options.config, options.namingScheme, options.Parallelism)
+	if err = a.load(); err != nil {
+		fatalf(exitStorageError, "%s", err)
+	}
+
+	tasks := make([]func() error, 0, len(a.paths))
+	for _, name := range a.paths {
+		name := name
+		download := a.downloads[name]
+		if !rewriteDocument(download, from, to) {
+			report.Document(name, true, false)
+			continue
+		}
+		entry := log.WithFields(download.LogFields()).WithField("path", name)
+		if options.DryRun {
+			entry.Info("would rewrite urls")
+			report.Document(name, true, true)
+			continue
+		}
+		tasks = append(tasks, func() error {
+			if err := writeJson(options.bucket, name, download, versions.DownloadDocument, registry.CacheControl); err != nil {
+				return err
+			}
+			entry.Info("rewrote urls")
+			report.Document(name, true, true)
+			return nil
+		})
+	}
+	if err = combineErrors(runParallel(options.Parallelism, tasks)); err != nil {
+		fatalf(exitCodeOf(err), "%s", err)
+	}
+
+	if options.DryRun {
+		options.mutex.Close()
+		options.storage.Close()
+		report.Status = "dry-run"
+		report.Exit(exitPublished)
+	}
+	closeBucketAndExit(options)
+}
@@ -0,0 +1,34 @@
+package main
+
+import (
+	"github.com/mollie/tf-provider-registry-api-generator/versions"
+	"testing"
+)
+
+func TestRewriteDocument(t *testing.T) {
+	sourceURL := "https://old.example.com/security/pgp-keys"
+	download := versions.BinaryMetaData{
+		DownloadURL:         "https://old.example.com/binaries/terraform-provider-mollie_1.0.0_linux_amd64.zip",
+		ShasumsURL:          "https://old.example.com/binaries/terraform-provider-mollie_1.0.0_SHA256SUMS",
+		ShasumsSignatureURL: "https://old.example.com.evil/binaries/terraform-provider-mollie_1.0.0_SHA256SUMS.sig",
+	}
+	download.SigningKeys.GpgPublicKeys = []versions.GpgSigningKey{{KeyID: "B64689ABE6ED9C52", SourceURL: &sourceURL}, {KeyID: "51229EAFE0F52100"}}
+
+	if !rewriteDocument(&download, "https://old.example.com", "https://new.example.com") {
+		t.Fatalf("expected the document to change")
+	}
+	if download.DownloadURL != "https://new.example.com/binaries/terraform-provider-mollie_1.0.0_linux_amd64.zip" ||
+		download.ShasumsURL != "https://new.example.com/binaries/terraform-provider-mollie_1.0.0_SHA256SUMS" {
+		t.Errorf("expected the urls to be rewritten, got %s, %s", download.DownloadURL, download.ShasumsURL)
+	}
+	if download.ShasumsSignatureURL != "https://old.example.com.evil/binaries/terraform-provider-mollie_1.0.0_SHA256SUMS.sig" {
+		t.Errorf("expected a url of another host to be kept, got %s", download.ShasumsSignatureURL)
+	}
+	if *download.SigningKeys.GpgPublicKeys[0].SourceURL != "https://new.example.com/security/pgp-keys" {
+		t.Errorf("expected the source url to be rewritten, got %s", *download.SigningKeys.GpgPublicKeys[0].SourceURL)
+	}
+
+	if rewriteDocument(&download, "https://old.example.com", "https://new.example.com") {
+		t.Errorf("expected no change on a second rewrite")
+	}
+}
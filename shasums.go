@@ -0,0 +1,93 @@
+package main
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"github.com/mollie/tf-provider-registry-api-generator/versions"
+	"hash"
+	"io"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// generateShasums creates the SHA256SUMS of each release which does not have one, by
+// computing the SHA-256 of the provider archives with shasumOf, at most parallelism at a time.
+// The generated file is stored next to the archives with write. It returns the file list
+// including the generated SHA256SUMS.
+func generateShasums(scheme *versions.NamingScheme, files []string, shasums map[string]string, parallelism int,
+	shasumOf func(filename string) (string, error), write func(filename string, content []byte) error) ([]string, error) {
+	existing := make(map[string]bool, len(files))
+	for _, filename := range files {
+		existing[filename] = true
+	}
+
+	releases := make(map[string][]string)
+	for _, filename := range files {
+		release := scheme.ParseArchive(filename)
+		if release == nil {
+			continue
+		}
+		shasumsFile := path.Join(path.Dir(filename), scheme.ShasumsFileName(release.TypeName, release.Version))
+		if !existing[shasumsFile] {
+			releases[shasumsFile] = append(releases[shasumsFile], filename)
+		}
+	}
+
+	var mutex sync.Mutex
+	computed := make(map[string]string)
+	tasks := make([]func() error, 0)
+	for _, archives := range releases {
+		for _, archive := range archives {
+			archive := archive
+			tasks = append(tasks, func() error {
+				shasum, err := shasumOf(archive)
+				if err != nil {
+					return err
+				}
+				mutex.Lock()
+				computed[archive] = shasum
+				mutex.Unlock()
+				return nil
+			})
+		}
+	}
+	if err := combineErrors(runParallel(parallelism, tasks)); err != nil {
+		return nil, err
+	}
+
+	shasumsFiles := make([]string, 0, len(releases))
+	for shasumsFile := range releases {
+		shasumsFiles = append(shasumsFiles, shasumsFile)
+	}
+	sort.Strings(shasumsFiles)
+
+	result := files
+	for _, shasumsFile := range shasumsFiles {
+		archives := releases[shasumsFile]
+		sort.Strings(archives)
+		var content strings.Builder
+		for _, archive := range archives {
+			shasums[archive] = computed[archive]
+			fmt.Fprintf(&content, "%s  %s\n", computed[archive], path.Base(archive))
+		}
+		if err := write(shasumsFile, []byte(content.String())); err != nil {
+			return nil, err
+		}
+		result = append(result, shasumsFile)
+	}
+	return result, nil
+}
+
+func computeShasum(bucket *Bucket, filename string) (string, error) {
+	var digest hash.Hash
+	err := bucket.ReadTo(filename, func() io.Writer {
+		digest = sha256.New()
+		return digest
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to read file %s, %s", filename, err)
+	}
+	return fmt.Sprintf("%x", digest.Sum(nil)), nil
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"github.com/mollie/tf-provider-registry-api-generator/versions"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+func TestGenerateShasums(t *testing.T) {
+	shasumOf := func(filename string) (string, error) {
+		return fmt.Sprintf("%x", sha256.Sum256([]byte(filename))), nil
+	}
+	var mutex sync.Mutex
+	written := make(map[string]string)
+	write := func(filename string, content []byte) error {
+		mutex.Lock()
+		defer mutex.Unlock()
+		written[filename] = string(content)
+		return nil
+	}
+
+	files := []string{
+		"binaries/mollie/terraform-provider-mollie/v1.0.0/terraform-provider-mollie_1.0.0_linux_amd64.zip",
+		"binaries/mollie/terraform-provider-mollie/v1.0.0/terraform-provider-mollie_1.0.0_darwin_amd64.zip",
+		"binaries/other/terraform-provider-mollie/v1.0.0/terraform-provider-mollie_1.0.0_linux_amd64.zip",
+		"binaries/signed/terraform-provider-mollie/v1.0.0/terraform-provider-mollie_1.0.0_linux_amd64.zip",
+		"binaries/signed/terraform-provider-mollie/v1.0.0/terraform-provider-mollie_1.0.0_SHA256SUMS",
+	}
+	shasums := make(map[string]string)
+	result, err := generateShasums(versions.DefaultNamingScheme(), files, shasums, 2, shasumOf, write)
+	if err != nil {
+		t.Fatalf("unexpected error, %s", err)
+	}
+
+	expectedFiles := append(append([]string{}, files...),
+		"binaries/mollie/terraform-provider-mollie/v1.0.0/terraform-provider-mollie_1.0.0_SHA256SUMS",
+		"binaries/other/terraform-provider-mollie/v1.0.0/terraform-provider-mollie_1.0.0_SHA256SUMS",
+	)
+	if !reflect.DeepEqual(result, expectedFiles) {
+		t.Errorf("expected files %v, got %v", expectedFiles, result)
+	}
+
+	shasum := func(filename string) string {
+		s, _ := shasumOf(filename)
+		return s
+	}
+	expected := map[string]string{
+		"binaries/mollie/terraform-provider-mollie/v1.0.0/terraform-provider-mollie_1.0.0_SHA256SUMS": shasum(files[1]) + "  terraform-provider-mollie_1.0.0_darwin_amd64.zip\n" +
+			shasum(files[0]) + "  terraform-provider-mollie_1.0.0_linux_amd64.zip\n",
+		"binaries/other/terraform-provider-mollie/v1.0.0/terraform-provider-mollie_1.0.0_SHA256SUMS": shasum(files[2]) + "  terraform-provider-mollie_1.0.0_linux_amd64.zip\n",
+	}
+	if !reflect.DeepEqual(written, expected) {
+		t.Errorf("expected SHA256SUMS %v, got %v", expected, written)
+	}
+	if len(shasums) != 3 || shasums[files[2]] != shasum(files[2]) {
+		t.Errorf("expected the shasums of the three archives without SHA256SUMS, got %v", shasums)
+	}
+
+	failing := func(filename string) (string, error) { return "", fmt.Errorf("failed to read file %s", filename) }
+	if _, err = generateShasums(versions.DefaultNamingScheme(), files, shasums, 2, failing, write); err == nil {
+		t.Errorf("expected an error if an archive cannot be read")
+	}
+}
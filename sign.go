@@ -0,0 +1,89 @@
+package main
+
+import (
+	"github.com/mollie/tf-provider-registry-api-generator/signing_key"
+	"github.com/mollie/tf-provider-registry-api-generator/versions"
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+)
+
+// loadPrivateSigningKey reads the private key from the file, or from the environment variable
+// GPG_SIGNING_KEY if no file is specified. The passphrase is read from the passphrase file or
+// from the environment variable GPG_SIGNING_KEY_PASSPHRASE.
+func loadPrivateSigningKey(keyFile string, passphraseFile string) *signing_key.PrivateSigningKey {
+	armored := os.Getenv("GPG_SIGNING_KEY")
+	if keyFile != "" {
+		content, err := ioutil.ReadFile(keyFile)
+		if err != nil {
+			fatalf(exitError, "failed to read signing key, %s", err)
+		}
+		armored = string(content)
+	}
+	if armored == "" {
+		fatalf(exitError, "no signing key specified")
+	}
+
+	passphrase := os.Getenv("GPG_SIGNING_KEY_PASSPHRASE")
+	if passphraseFile != "" {
+		content, err := ioutil.ReadFile(passphraseFile)
+		if err != nil {
+			fatalf(exitError, "failed to read passphrase, %s", err)
+		}
+		passphrase = strings.TrimRight(string(content), "\r\n")
+	}
+
+	key, err := signing_key.ReadPrivateSigningKey(armored, passphrase)
+	if err != nil {
+		fatalf(exitError, "%s", err)
+	}
+	return key
+}
+
+// addPublicSigningKey adds the public key of the private key to the signing keys, unless it
+// is already present. A configured key may be identified by its fingerprint or by a short or
+// long key id, so the armored keys are compared by their key ids.
+func addPublicSigningKey(signingKeys []signing_key.PGPSigningKey, key *signing_key.PrivateSigningKey) []signing_key.PGPSigningKey {
+	for _, k := range signingKeys {
+		if strings.EqualFold(k.KeyID, key.Fingerprint()) || k.HasKeyID(key.KeyID()) {
+			return signingKeys
+		}
+	}
+	publicKey, err := key.PublicKey()
+	if err != nil {
+		fatalf(exitError, "%s", err)
+	}
+	return append(signingKeys, publicKey)
+}
+
+// signShasums creates a detached signature for each SHA256SUMS file which has no signature
+// yet, and uploads it next to the SHA256SUMS. It returns the file list including the signatures.
+func signShasums(bucket *Bucket, scheme *versions.NamingScheme, files []string, signatures map[string][]byte, key *signing_key.PrivateSigningKey) []string {
+	result := files
+	for _, filename := range files {
+		release := scheme.ParseShasums(filename)
+		if release == nil {
+			continue
+		}
+		signatureFile := path.Join(path.Dir(filename), scheme.SignatureFileName(release.TypeName, release.Version))
+		if _, ok := signatures[signatureFile]; ok {
+			continue
+		}
+
+		content, err := readObject(bucket, filename)
+		if err != nil {
+			fatalf(exitStorageError, "%s", err)
+		}
+		signature, err := key.Sign(content)
+		if err != nil {
+			fatalf(exitError, "failed to sign %s, %s", filename, err)
+		}
+		if err = writeObject(bucket, signatureFile, signature, "application/pgp-signature"); err != nil {
+			fatalf(exitStorageError, "%s", err)
+		}
+		signatures[signatureFile] = signature
+		result = append(result, signatureFile)
+	}
+	return result
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"github.com/mollie/tf-provider-registry-api-generator/internal/registrytest"
+	"github.com/mollie/tf-provider-registry-api-generator/signing_key"
+	"testing"
+)
+
+func TestAddPublicSigningKey(t *testing.T) {
+	key := registrytest.NewSigningKey(t, "publisher")
+	publicKey, err := key.PublicKey()
+	if err != nil {
+		t.Fatalf("failed to export public key, %s", err)
+	}
+	fingerprint := key.Fingerprint()
+	other, err := registrytest.NewSigningKey(t, "other").PublicKey()
+	if err != nil {
+		t.Fatalf("failed to export public key, %s", err)
+	}
+
+	tests := []struct {
+		name  string
+		keyID string
+		want  int
+	}{
+		{"fingerprint", fingerprint, 1},
+		{"long_key_id", fingerprint[24:], 1},
+		{"prefixed_long_key_id", "0x" + fingerprint[24:], 1},
+		{"short_key_id", fingerprint[32:], 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			configured := publicKey
+			configured.KeyID = tt.keyID
+			if got := addPublicSigningKey([]signing_key.PGPSigningKey{configured}, key); len(got) != tt.want {
+				t.Errorf("expected %d signing keys, got %d", tt.want, len(got))
+			}
+		})
+	}
+	if got := addPublicSigningKey([]signing_key.PGPSigningKey{other}, key); len(got) != 2 || got[1].KeyID != fingerprint {
+		t.Errorf("expected the public key to be added, got %v", got)
+	}
+}
@@ -1,40 +1,223 @@
 package signing_key
 
 import (
+	"bytes"
+	"fmt"
+	"golang.org/x/crypto/openpgp"
+	"golang.org/x/crypto/openpgp/armor"
+	"golang.org/x/crypto/openpgp/packet"
 	"io/ioutil"
-	"log"
+	log "github.com/sirupsen/logrus"
 	"os/exec"
+	"strings"
 )
 
 type PGPSigningKey struct {
-	KeyID      string
-	ASCIIArmor string
+	KeyID          string
+	ASCIIArmor     string
+	TrustSignature string
+	Source         string
+	SourceURL      string
 }
 
-func GetPublicSigningKey(fingerPrint string) PGPSigningKey {
+// GetPublicSigningKey exports the ASCII armored public key of the fingerprint from the gpg
+// keyring. The key may also be referred to by a short or long key id, or by an email address,
+// so the key id of the result is the fingerprint of the exported primary key.
+func GetPublicSigningKey(fingerPrint string) (PGPSigningKey, error) {
+	primary, err := gpgFingerprint(fingerPrint)
+	if err != nil {
+		return PGPSigningKey{}, err
+	}
+	cmd := exec.Command("gpg", "--armor", "--export", primary)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	key, err := cmd.Output()
+	if err != nil {
+		return PGPSigningKey{}, fmt.Errorf("failed to export public key %s, %s %s", fingerPrint, err, strings.TrimSpace(stderr.String()))
+	}
+	if len(key) == 0 {
+		return PGPSigningKey{}, fmt.Errorf("failed to retrieve public key %s, %s", fingerPrint, strings.TrimSpace(stderr.String()))
+	}
+	return PGPSigningKey{KeyID: primary, ASCIIArmor: string(key)}, nil
+}
 
+// gpgFingerprint returns the fingerprint of the primary key in the gpg keyring referred to by
+// the key id. It returns an error if the key id refers to no key or to more than one key.
+func gpgFingerprint(keyID string) (string, error) {
+	cmd := exec.Command("gpg", "--batch", "--with-colons", "--fingerprint", keyID)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	output, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("failed to find public key %s, %s %s", keyID, err, strings.TrimSpace(stderr.String()))
+	}
+	fingerprints := make([]string, 0, 1)
+	primary := false
+	for _, line := range strings.Split(string(output), "\n") {
+		// the first fpr record after a pub record is the fingerprint of the primary key
+		fields := strings.Split(line, ":")
+		if fields[0] == "pub" {
+			primary = true
+		} else if fields[0] == "fpr" && primary && len(fields) > 9 {
+			fingerprints = append(fingerprints, strings.ToUpper(fields[9]))
+			primary = false
+		}
+	}
+	if len(fingerprints) != 1 {
+		return "", fmt.Errorf("expected a single public key for %s, found %d", keyID, len(fingerprints))
+	}
+	return fingerprints[0], nil
+}
 
-	cmd := exec.Command("gpg", "--armor", "--export", fingerPrint)
-	stdout, err := cmd.StdoutPipe()
+// SetSource sets the source name, url and trust signature of all keys. The trust signature
+// is the ASCII armored signature of the key made by the namespace key.
+func SetSource(keys []PGPSigningKey, source string, sourceURL string, trustSignature string) {
+	for i := range keys {
+		keys[i].Source = source
+		keys[i].SourceURL = sourceURL
+		keys[i].TrustSignature = trustSignature
+	}
+}
+
+// HasKeyID returns true if the primary key or one of the subkeys has the key id.
+func (k PGPSigningKey) HasKeyID(keyID uint64) bool {
+	entities, err := openpgp.ReadArmoredKeyRing(strings.NewReader(k.ASCIIArmor))
 	if err != nil {
-		log.Fatalf("%s", err)
+		log.WithField("key_id", k.KeyID).Warnf("failed to read public key, %s", err)
+		return false
+	}
+	return len(entities.KeysById(keyID)) > 0
+}
+
+// IssuerKeyID returns the id of the key which created the detached signature. The
+// signature may be binary or ASCII armored.
+func IssuerKeyID(signature []byte) (uint64, error) {
+	var reader = bytes.NewReader(signature)
+	if bytes.HasPrefix(bytes.TrimSpace(signature), []byte("-----BEGIN")) {
+		block, err := armor.Decode(reader)
+		if err != nil {
+			return 0, fmt.Errorf("failed to decode armored signature, %s", err)
+		}
+		content, err := ioutil.ReadAll(block.Body)
+		if err != nil {
+			return 0, fmt.Errorf("failed to read armored signature, %s", err)
+		}
+		reader = bytes.NewReader(content)
 	}
-	stderr, err := cmd.StderrPipe()
+
+	p, err := packet.Read(reader)
 	if err != nil {
-		log.Fatalf("%s", err)
+		return 0, fmt.Errorf("failed to read signature packet, %s", err)
 	}
+	switch sig := p.(type) {
+	case *packet.Signature:
+		if sig.IssuerKeyId == nil {
+			return 0, fmt.Errorf("signature has no issuer key id")
+		}
+		return *sig.IssuerKeyId, nil
+	case *packet.SignatureV3:
+		return sig.IssuerKeyId, nil
+	default:
+		return 0, fmt.Errorf("expected a signature packet, found %T", p)
+	}
+}
 
-	err = cmd.Start()
+// FindSigningKey returns the key which created the detached signature.
+func FindSigningKey(keys []PGPSigningKey, signature []byte) (*PGPSigningKey, error) {
+	keyID, err := IssuerKeyID(signature)
 	if err != nil {
-		log.Fatalf("%s", err)
+		return nil, err
+	}
+	for i, key := range keys {
+		if key.HasKeyID(keyID) {
+			return &keys[i], nil
+		}
 	}
-	key, err := ioutil.ReadAll(stdout)
+	return nil, fmt.Errorf("signed with key id %016X, which is not one of the signing keys", keyID)
+}
+
+// VerifySignature checks that the detached signature of the content was created by the
+// ASCII armored public key. The signature may be binary or ASCII armored.
+func VerifySignature(armoredKey string, content []byte, signature []byte) error {
+	keyring, err := openpgp.ReadArmoredKeyRing(strings.NewReader(armoredKey))
 	if err != nil {
-		log.Fatalf("%s", err)
+		return fmt.Errorf("failed to read public key, %s", err)
 	}
-	if len(key) == 0 {
-		msg,_ := ioutil.ReadAll(stderr)
-		log.Fatalf("ERROR: failed to retrieve public key %s, %s", fingerPrint, string(msg))
+	if bytes.HasPrefix(bytes.TrimSpace(signature), []byte("-----BEGIN")) {
+		_, err = openpgp.CheckArmoredDetachedSignature(keyring, bytes.NewReader(content), bytes.NewReader(signature))
+	} else {
+		_, err = openpgp.CheckDetachedSignature(keyring, bytes.NewReader(content), bytes.NewReader(signature))
+	}
+	return err
+}
+
+// PrivateSigningKey is used to sign the SHA256SUMS of releases.
+type PrivateSigningKey struct {
+	entity *openpgp.Entity
+}
+
+// ReadPrivateSigningKey reads an ASCII armored private key, and decrypts it with the passphrase
+// if it is encrypted.
+func ReadPrivateSigningKey(armored string, passphrase string) (*PrivateSigningKey, error) {
+	entities, err := openpgp.ReadArmoredKeyRing(strings.NewReader(armored))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read private key, %s", err)
+	}
+	if len(entities) != 1 {
+		return nil, fmt.Errorf("expected a single private key, found %d", len(entities))
+	}
+	entity := entities[0]
+	if entity.PrivateKey == nil {
+		return nil, fmt.Errorf("key %X is not a private key", entity.PrimaryKey.Fingerprint)
+	}
+
+	keys := []*packet.PrivateKey{entity.PrivateKey}
+	for _, subkey := range entity.Subkeys {
+		if subkey.PrivateKey != nil {
+			keys = append(keys, subkey.PrivateKey)
+		}
+	}
+	for _, key := range keys {
+		if key.Encrypted {
+			if err = key.Decrypt([]byte(passphrase)); err != nil {
+				return nil, fmt.Errorf("failed to decrypt private key %X, %s", key.Fingerprint, err)
+			}
+		}
+	}
+	return &PrivateSigningKey{entity: entity}, nil
+}
+
+// Fingerprint of the primary key.
+func (k *PrivateSigningKey) Fingerprint() string {
+	return fmt.Sprintf("%X", k.entity.PrimaryKey.Fingerprint)
+}
+
+// KeyID of the primary key.
+func (k *PrivateSigningKey) KeyID() uint64 {
+	return k.entity.PrimaryKey.KeyId
+}
+
+// PublicKey returns the ASCII armored public key of the private key.
+func (k *PrivateSigningKey) PublicKey() (PGPSigningKey, error) {
+	var buffer bytes.Buffer
+	w, err := armor.Encode(&buffer, openpgp.PublicKeyType, nil)
+	if err != nil {
+		return PGPSigningKey{}, err
+	}
+	if err = k.entity.Serialize(w); err != nil {
+		return PGPSigningKey{}, fmt.Errorf("failed to serialize public key, %s", err)
+	}
+	if err = w.Close(); err != nil {
+		return PGPSigningKey{}, err
+	}
+	return PGPSigningKey{KeyID: k.Fingerprint(), ASCIIArmor: buffer.String()}, nil
+}
+
+// Sign returns a detached binary signature of the content.
+func (k *PrivateSigningKey) Sign(content []byte) ([]byte, error) {
+	var signature bytes.Buffer
+	if err := openpgp.DetachSign(&signature, k.entity, bytes.NewReader(content), nil); err != nil {
+		return nil, fmt.Errorf("failed to sign, %s", err)
 	}
-	return PGPSigningKey{fingerPrint, string(key)}
+	return signature.Bytes(), nil
 }
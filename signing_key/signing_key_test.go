@@ -0,0 +1,113 @@
+package signing_key
+
+import (
+	"bytes"
+	"golang.org/x/crypto/openpgp"
+	"golang.org/x/crypto/openpgp/armor"
+	"golang.org/x/crypto/openpgp/packet"
+	"testing"
+)
+
+func generateKey(t *testing.T, name string) (*openpgp.Entity, PGPSigningKey) {
+	entity, err := openpgp.NewEntity(name, "", name+"@example.com", &packet.Config{RSABits: 1024})
+	if err != nil {
+		t.Fatalf("failed to generate key, %s", err)
+	}
+	var buffer bytes.Buffer
+	w, err := armor.Encode(&buffer, openpgp.PublicKeyType, nil)
+	if err != nil {
+		t.Fatalf("failed to armor key, %s", err)
+	}
+	if err = entity.Serialize(w); err != nil {
+		t.Fatalf("failed to serialize key, %s", err)
+	}
+	w.Close()
+	return entity, PGPSigningKey{KeyID: entity.PrimaryKey.KeyIdString(), ASCIIArmor: buffer.String()}
+}
+
+func TestFindSigningKey(t *testing.T) {
+	oldEntity, oldKey := generateKey(t, "old")
+	newEntity, newKey := generateKey(t, "new")
+	otherEntity, _ := generateKey(t, "other")
+	content := []byte("a2c5881ea67e1c397cb26c6162d81829e058d5a993801bcb69df9982412d27e9  terraform-provider-sentry_0.6.0_darwin_amd64.zip\n")
+
+	tests := []struct {
+		name    string
+		signer  *openpgp.Entity
+		armored bool
+		want    *PGPSigningKey
+	}{
+		{"old_key", oldEntity, false, &oldKey},
+		{"new_key", newEntity, false, &newKey},
+		{"armored_signature", newEntity, true, &newKey},
+		{"unknown_key", otherEntity, false, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var signature bytes.Buffer
+			var err error
+			if tt.armored {
+				err = openpgp.ArmoredDetachSign(&signature, tt.signer, bytes.NewReader(content), nil)
+			} else {
+				err = openpgp.DetachSign(&signature, tt.signer, bytes.NewReader(content), nil)
+			}
+			if err != nil {
+				t.Fatalf("failed to sign, %s", err)
+			}
+
+			key, err := FindSigningKey([]PGPSigningKey{oldKey, newKey}, signature.Bytes())
+			if tt.want == nil {
+				if err == nil {
+					t.Errorf("expected an error, found key %s", key.KeyID)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error, %s", err)
+			}
+			if key.KeyID != tt.want.KeyID {
+				t.Errorf("expected key %s, found %s", tt.want.KeyID, key.KeyID)
+			}
+		})
+	}
+}
+
+func TestPrivateSigningKey_Sign(t *testing.T) {
+	entity, _ := generateKey(t, "private")
+	var buffer bytes.Buffer
+	w, err := armor.Encode(&buffer, openpgp.PrivateKeyType, nil)
+	if err != nil {
+		t.Fatalf("failed to armor key, %s", err)
+	}
+	if err = entity.SerializePrivate(w, nil); err != nil {
+		t.Fatalf("failed to serialize private key, %s", err)
+	}
+	w.Close()
+
+	key, err := ReadPrivateSigningKey(buffer.String(), "")
+	if err != nil {
+		t.Fatalf("failed to read private key, %s", err)
+	}
+	publicKey, err := key.PublicKey()
+	if err != nil {
+		t.Fatalf("failed to get public key, %s", err)
+	}
+	signature, err := key.Sign([]byte("content"))
+	if err != nil {
+		t.Fatalf("failed to sign, %s", err)
+	}
+
+	found, err := FindSigningKey([]PGPSigningKey{publicKey}, signature)
+	if err != nil {
+		t.Fatalf("signature not created by the public key, %s", err)
+	}
+	if found.KeyID != key.Fingerprint() {
+		t.Errorf("expected key %s, found %s", key.Fingerprint(), found.KeyID)
+	}
+	if err = VerifySignature(publicKey.ASCIIArmor, []byte("content"), signature); err != nil {
+		t.Errorf("invalid signature, %s", err)
+	}
+	if err = VerifySignature(publicKey.ASCIIArmor, []byte("other content"), signature); err == nil {
+		t.Errorf("expected the signature of other content to be invalid")
+	}
+}
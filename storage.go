@@ -0,0 +1,177 @@
+package main
+
+import (
+	"bytes"
+	"cloud.google.com/go/storage"
+	"context"
+	"errors"
+	"fmt"
+	log "github.com/sirupsen/logrus"
+	"google.golang.org/api/googleapi"
+	"google.golang.org/api/iterator"
+	"io"
+	"io/ioutil"
+	"net"
+	"time"
+)
+
+// errObjectNotExist is returned when reading an object which does not exist.
+var errObjectNotExist = storage.ErrObjectNotExist
+
+// Bucket performs the storage operations on a bucket. Each operation runs with a timeout,
+// and is retried with exponential backoff on transient errors. Once the context of the
+// bucket is cancelled, no new operations are started, but operations in-flight are completed.
+type Bucket struct {
+	Name    string
+	handle  *storage.BucketHandle
+	ctx     context.Context
+	timeout time.Duration
+	retries int
+	backoff time.Duration
+}
+
+// NewBucket returns a bucket with the specified timeout per operation and number of retries.
+func NewBucket(ctx context.Context, client *storage.Client, name string, timeout time.Duration, retries int) *Bucket {
+	return &Bucket{
+		Name:    name,
+		handle:  client.Bucket(name),
+		ctx:     ctx,
+		timeout: timeout,
+		retries: retries,
+		backoff: 500 * time.Millisecond,
+	}
+}
+
+// isRetryable returns true for errors which may be resolved by retrying the operation.
+func isRetryable(err error) bool {
+	if err == nil {
+		return false
+	}
+	var apiError *googleapi.Error
+	if errors.As(err, &apiError) {
+		return apiError.Code == 408 || apiError.Code == 429 || apiError.Code >= 500
+	}
+	var netError net.Error
+	if errors.As(err, &netError) {
+		return true
+	}
+	return errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, context.DeadlineExceeded)
+}
+
+// do runs the operation, retrying it with exponential backoff on transient errors.
+func (b *Bucket) do(name string, operation func(ctx context.Context) error) error {
+	backoff := b.backoff
+	for attempt := 0; ; attempt++ {
+		if err := b.ctx.Err(); err != nil {
+			return fmt.Errorf("%s not started, %s", name, err)
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), b.timeout)
+		err := operation(ctx)
+		cancel()
+
+		if err == nil || !isRetryable(err) || attempt >= b.retries {
+			return err
+		}
+		log.WithFields(log.Fields{"operation": name, "retry_in": backoff.String()}).Warnf("storage request failed, %s", err)
+		select {
+		case <-time.After(backoff):
+		case <-b.ctx.Done():
+			return err
+		}
+		backoff *= 2
+	}
+}
+
+// Read returns the content of the object, or errObjectNotExist if it does not exist.
+func (b *Bucket) Read(filename string) ([]byte, error) {
+	var content []byte
+	err := b.do("read "+filename, func(ctx context.Context) error {
+		r, err := b.handle.Object(filename).NewReader(ctx)
+		if err != nil {
+			return err
+		}
+		defer r.Close()
+		content, err = ioutil.ReadAll(r)
+		return err
+	})
+	return content, err
+}
+
+// ReadTo copies the content of the object to the writer returned by newWriter. The writer
+// is recreated for each attempt.
+func (b *Bucket) ReadTo(filename string, newWriter func() io.Writer) error {
+	return b.do("read "+filename, func(ctx context.Context) error {
+		r, err := b.handle.Object(filename).NewReader(ctx)
+		if err != nil {
+			return err
+		}
+		defer r.Close()
+		_, err = io.Copy(newWriter(), r)
+		return err
+	})
+}
+
+// Write stores the content in the object.
+func (b *Bucket) Write(filename string, content []byte, contentType string, cacheControl string) error {
+	return b.do("write "+filename, func(ctx context.Context) error {
+		w := b.handle.Object(filename).NewWriter(ctx)
+		w.ContentType = contentType
+		w.CacheControl = cacheControl
+		if _, err := io.Copy(w, bytes.NewReader(content)); err != nil {
+			w.Close()
+			return err
+		}
+		return w.Close()
+	})
+}
+
+// List returns the names of all objects with the prefix.
+func (b *Bucket) List(prefix string) ([]string, error) {
+	var names []string
+	err := b.do("list "+prefix, func(ctx context.Context) error {
+		names = make([]string, 0)
+		it := b.handle.Objects(ctx, &storage.Query{Prefix: prefix})
+		for {
+			attrs, err := it.Next()
+			if err == iterator.Done {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+			names = append(names, attrs.Name)
+		}
+	})
+	return names, err
+}
+
+// Exists returns true if the object exists.
+func (b *Bucket) Exists(filename string) (bool, error) {
+	exists := false
+	err := b.do("stat "+filename, func(ctx context.Context) error {
+		_, err := b.handle.Object(filename).Attrs(ctx)
+		if errors.Is(err, errObjectNotExist) {
+			exists = false
+			return nil
+		}
+		exists = err == nil
+		return err
+	})
+	return exists, err
+}
+
+// Delete removes the object.
+func (b *Bucket) Delete(filename string) error {
+	return b.do("delete "+filename, func(ctx context.Context) error {
+		return b.handle.Object(filename).Delete(ctx)
+	})
+}
+
+// Uncancellable returns a bucket which continues to start operations after the context
+// of this bucket is cancelled. It is used to complete a rollback.
+func (b *Bucket) Uncancellable() *Bucket {
+	result := *b
+	result.ctx = context.Background()
+	return &result
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"google.golang.org/api/googleapi"
+	"testing"
+	"time"
+)
+
+func TestBucket_do(t *testing.T) {
+	tests := []struct {
+		name     string
+		errors   []error
+		wantErr  bool
+		attempts int
+	}{
+		{"success", []error{nil}, false, 1},
+		{"retry_unavailable", []error{&googleapi.Error{Code: 503}, &googleapi.Error{Code: 429}, nil}, false, 3},
+		{"no_retry_on_not_found", []error{&googleapi.Error{Code: 404}}, true, 1},
+		{"no_retry_on_permanent_error", []error{errObjectNotExist}, true, 1},
+		{"retries_exhausted", []error{
+			&googleapi.Error{Code: 500}, &googleapi.Error{Code: 500}, &googleapi.Error{Code: 500},
+			&googleapi.Error{Code: 500}}, true, 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bucket := &Bucket{ctx: context.Background(), timeout: time.Second, retries: 2, backoff: time.Millisecond}
+			attempts := 0
+			err := bucket.do("test", func(ctx context.Context) error {
+				attempts++
+				return tt.errors[attempts-1]
+			})
+			if (err != nil) != tt.wantErr {
+				t.Errorf("expected error %v, got %v", tt.wantErr, err)
+			}
+			if attempts != tt.attempts {
+				t.Errorf("expected %d attempts, got %d", tt.attempts, attempts)
+			}
+		})
+	}
+}
+
+func TestBucket_doCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	bucket := &Bucket{ctx: ctx, timeout: time.Second, retries: 2, backoff: time.Millisecond}
+	err := bucket.do("test", func(ctx context.Context) error {
+		return errors.New("should not be called")
+	})
+	if err == nil || err.Error() == "should not be called" {
+		t.Errorf("expected the operation not to start, got %v", err)
+	}
+}
@@ -0,0 +1,234 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/mollie/tf-provider-registry-api-generator/versions"
+	log "github.com/sirupsen/logrus"
+	"path"
+	"sort"
+)
+
+// document is an API document to be published.
+type document struct {
+	path     string
+	fields   log.Fields
+	object   interface{}
+	content  []byte
+	previous []byte
+	existed  bool
+	changed  bool
+	written  bool
+}
+
+// publication publishes the API documents of a release as a transaction. All documents are
+// built and validated in memory. The download documents are written and verified before the
+// versions documents are updated. If any step fails, the written documents are rolled back.
+type publication struct {
+	bucket      *Bucket
+	registry    *RegistryConfig
+	parallelism int
+	downloads   []*document
+	versions    []*document
+}
+
+func marshalDocument(object interface{}) ([]byte, error) {
+	var buffer bytes.Buffer
+	encoder := json.NewEncoder(&buffer)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(object); err != nil {
+		return nil, err
+	}
+	return buffer.Bytes(), nil
+}
+
+// log returns a log entry with the fields of the document.
+func (d *document) log() *log.Entry {
+	return log.WithFields(d.fields).WithField("path", d.path)
+}
+
+// readPrevious reads the current content of the document, if it exists.
+func (d *document) readPrevious(bucket *Bucket) error {
+	content, err := bucket.Read(d.path)
+	if err != nil {
+		if errors.Is(err, errObjectNotExist) {
+			return nil
+		}
+		return fmt.Errorf("failed to read file %s, %s", d.path, err)
+	}
+	d.previous = content
+	d.existed = true
+	return nil
+}
+
+// prepare reads the existing documents and builds the new documents in memory.
+func (p *publication) prepare(binaries versions.BinaryMetaDataList) error {
+	providerDirectory := p.registry.ProvidersPath()
+	providers := binaries.ExtractVersions()
+
+	tasks := make([]func() error, 0, len(binaries)+len(providers))
+	for i := range binaries {
+		binary := &binaries[i]
+		d := &document{
+			path:   path.Join(providerDirectory, binary.Namespace, binary.TypeName, binary.Version, "download", binary.Os, binary.Arch),
+			fields: binary.LogFields(),
+			object: binary,
+		}
+		p.downloads = append(p.downloads, d)
+		tasks = append(tasks, func() error {
+			if err := d.readPrevious(p.bucket); err != nil {
+				return err
+			}
+			var existing versions.BinaryMetaData
+			if d.existed {
+				if err := json.Unmarshal(d.previous, &existing); err != nil {
+					return fmt.Errorf("failed to unmarshal %s, %s", d.path, err)
+				}
+			}
+			d.changed = !d.existed || !existing.Equals(binary)
+			return nil
+		})
+	}
+
+	names := make([]string, 0, len(providers))
+	for name := range providers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		newVersions := providers[name]
+		d := &document{path: path.Join(providerDirectory, name, "versions"), fields: providerFields(name)}
+		p.versions = append(p.versions, d)
+		tasks = append(tasks, func() error {
+			if err := d.readPrevious(p.bucket); err != nil {
+				return err
+			}
+			return d.mergeVersions(newVersions)
+		})
+	}
+
+	if err := combineErrors(runParallel(p.parallelism, tasks)); err != nil {
+		return err
+	}
+
+	for _, d := range p.downloads {
+		content, err := marshalDocument(d.object)
+		if err != nil {
+			return fmt.Errorf("failed to marshal %s, %s", d.path, err)
+		}
+		d.content = content
+	}
+	return nil
+}
+
+// mergeVersions merges the new versions into the previous content of the versions document.
+// The document is only changed if the merged document differs from the previous content, as
+// the new versions may be a subset of the published versions.
+func (d *document) mergeVersions(newVersions *versions.ProviderVersions) error {
+	var merged versions.ProviderVersions
+	if d.existed {
+		if err := json.Unmarshal(d.previous, &merged); err != nil {
+			return fmt.Errorf("failed to unmarshal %s, %s", d.path, err)
+		}
+	}
+	merged.Merge(*newVersions)
+	content, err := marshalDocument(&merged)
+	if err != nil {
+		return fmt.Errorf("failed to marshal %s, %s", d.path, err)
+	}
+	d.object = &merged
+	d.content = content
+	d.changed = !d.existed || !bytes.Equal(content, d.previous)
+	return nil
+}
+
+// validate checks all documents against their JSON schema and the provider registry protocol.
+func (p *publication) validate() error {
+	errs := make([]error, 0)
+	for _, d := range p.downloads {
+		if err := versions.ValidateDocument(versions.DownloadDocument, d.content); err != nil {
+			errs = append(errs, fmt.Errorf("%s, %s", d.path, err))
+		}
+	}
+	for _, d := range p.versions {
+		if err := versions.ValidateDocument(versions.VersionsDocument, d.content); err != nil {
+			errs = append(errs, fmt.Errorf("%s, %s", d.path, err))
+		}
+	}
+	return combineErrors(errs)
+}
+
+// write writes and verifies the changed documents.
+func (p *publication) write(documents []*document) error {
+	tasks := make([]func() error, 0, len(documents))
+	for _, d := range documents {
+		if !d.changed {
+			d.log().Info("document is up-to-date")
+			continue
+		}
+		d := d
+		tasks = append(tasks, func() error {
+			d.log().Info("writing document")
+			d.written = true
+			if err := p.bucket.Write(d.path, d.content, "application/json", p.registry.CacheControl); err != nil {
+				return fmt.Errorf("failed to write %s, %s", d.path, err)
+			}
+			content, err := p.bucket.Read(d.path)
+			if err != nil {
+				return fmt.Errorf("failed to read back %s, %s", d.path, err)
+			}
+			if !bytes.Equal(content, d.content) {
+				return fmt.Errorf("content of %s differs from what was written", d.path)
+			}
+			return nil
+		})
+	}
+	return combineErrors(runParallel(p.parallelism, tasks))
+}
+
+// commit writes the download documents followed by the versions documents.
+func (p *publication) commit() error {
+	if err := p.write(p.downloads); err != nil {
+		return err
+	}
+	return p.write(p.versions)
+}
+
+// record adds the documents of the committed publication to the report.
+func (p *publication) record() {
+	for _, d := range append(append([]*document{}, p.downloads...), p.versions...) {
+		report.Document(d.path, d.existed, d.changed)
+	}
+}
+
+// rollback deletes the documents which were created, and restores the previous content of
+// the documents which were updated.
+func (p *publication) rollback() {
+	bucket := p.bucket.Uncancellable()
+	tasks := make([]func() error, 0)
+	for _, d := range append(append([]*document{}, p.downloads...), p.versions...) {
+		if !d.written {
+			continue
+		}
+		d := d
+		tasks = append(tasks, func() error {
+			if !d.existed {
+				d.log().Info("rollback, deleting document")
+				if err := bucket.Delete(d.path); err != nil && !errors.Is(err, errObjectNotExist) {
+					return fmt.Errorf("failed to delete %s, %s", d.path, err)
+				}
+				return nil
+			}
+			d.log().Info("rollback, restoring document")
+			if err := bucket.Write(d.path, d.previous, "application/json", p.registry.CacheControl); err != nil {
+				return fmt.Errorf("failed to restore %s, %s", d.path, err)
+			}
+			return nil
+		})
+	}
+	if err := combineErrors(runParallel(p.parallelism, tasks)); err != nil {
+		log.Errorf("rollback failed, %s", err)
+	}
+}
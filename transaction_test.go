@@ -0,0 +1,49 @@
+package main
+
+import (
+	"github.com/mollie/tf-provider-registry-api-generator/versions"
+	"testing"
+)
+
+func TestDocument_mergeVersions(t *testing.T) {
+	version := func(v string) versions.ProviderVersion {
+		return versions.ProviderVersion{Version: v, Protocols: []string{"5.0"}, Platforms: []versions.Platform{{Os: "linux", Arch: "amd64"}}}
+	}
+	var published versions.ProviderVersions
+	published.AddProviderVersion(version("1.0.0"))
+	published.AddProviderVersion(version("1.1.0"))
+	previous, err := marshalDocument(&published)
+	if err != nil {
+		t.Fatalf("failed to marshal versions, %s", err)
+	}
+
+	tests := []struct {
+		name     string
+		existed  bool
+		versions []string
+		want     bool
+	}{
+		{"subset", true, []string{"1.1.0"}, false},
+		{"all", true, []string{"1.0.0", "1.1.0"}, false},
+		{"new_version", true, []string{"1.2.0"}, true},
+		{"new_document", false, []string{"1.0.0"}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := &document{path: "v1/providers/mollie/mollie/versions", existed: tt.existed}
+			if tt.existed {
+				d.previous = previous
+			}
+			var newVersions versions.ProviderVersions
+			for _, v := range tt.versions {
+				newVersions.AddProviderVersion(version(v))
+			}
+			if err := d.mergeVersions(&newVersions); err != nil {
+				t.Fatalf("unexpected error, %s", err)
+			}
+			if d.changed != tt.want {
+				t.Errorf("expected changed %v, got %v", tt.want, d.changed)
+			}
+		})
+	}
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"fmt"
+	"github.com/mollie/tf-provider-registry-api-generator/versions"
+	log "github.com/sirupsen/logrus"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// readDocument reads the document from a file, or from an http or https url.
+func readDocument(location string, timeout time.Duration) ([]byte, error) {
+	if !strings.HasPrefix(location, "http://") && !strings.HasPrefix(location, "https://") {
+		return ioutil.ReadFile(location)
+	}
+
+	client := newHTTPClient(timeout)
+	response, err := client.Get(location)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("GET %s returned %s", location, response.Status)
+	}
+	return ioutil.ReadAll(response.Body)
+}
+
+// validateDocuments checks the documents against the JSON schema of their kind and the
+// provider registry protocol, and exits.
+func validateDocuments(locations []string, timeout time.Duration) {
+	exitCode := exitPublished
+	for _, location := range locations {
+		entry := log.WithField("document", location)
+		content, err := readDocument(location, timeout)
+		if err != nil {
+			entry.Errorf("failed to read document, %s", err)
+			exitCode = exitError
+			continue
+		}
+
+		kind, err := versions.DocumentKindOf(location, content)
+		if err == nil {
+			entry = entry.WithField("kind", kind)
+			err = versions.ValidateDocument(kind, content)
+		}
+		if err != nil {
+			entry.Errorf("%s", err)
+			if exitCode == exitPublished {
+				exitCode = exitValidationFailed
+			}
+			continue
+		}
+		entry.Info("document is valid")
+	}
+
+	if exitCode == exitPublished {
+		report.Status = "valid"
+	}
+	report.Exit(exitCode)
+}
@@ -0,0 +1,82 @@
+package versions
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// DefaultArtifactURLTemplate serves the release files from the artifact base url, with the
+// same path as in the bucket.
+const DefaultArtifactURLTemplate = "{artifactBase}/{path}"
+
+var artifactPlaceholderExpression = regexp.MustCompile(`{[^}]*}`)
+
+// ArtifactURLTemplate describes the download urls of the release files, for example
+// {artifactBase}/{namespace}/{type}/{version}/{filename}. The placeholder {path} is the name
+// of the file in the bucket, so that the files can be served from the website of the registry
+// or from a CDN in front of the bucket.
+type ArtifactURLTemplate struct {
+	Template string
+	Base     string
+}
+
+// NewArtifactURLTemplate creates an artifact url template with the placeholders {artifactBase},
+// {path}, {namespace}, {type}, {version} and {filename}.
+func NewArtifactURLTemplate(template string, base string) (*ArtifactURLTemplate, error) {
+	for _, placeholder := range artifactPlaceholderExpression.FindAllString(template, -1) {
+		switch placeholder {
+		case "{artifactBase}", "{path}", "{namespace}", "{type}", "{version}", "{filename}":
+		default:
+			return nil, fmt.Errorf("the artifact url template %s contains the unknown placeholder %s", template, placeholder)
+		}
+	}
+	if !strings.Contains(template, "{path}") && !strings.Contains(template, "{filename}") {
+		return nil, fmt.Errorf("the artifact url template %s must contain {path} or {filename}", template)
+	}
+	return &ArtifactURLTemplate{Template: template, Base: strings.TrimRight(base, "/")}, nil
+}
+
+// URL returns the download url of the file in the bucket, which is a release file of the
+// provider version.
+func (t *ArtifactURLTemplate) URL(filename string, namespace string, typeName string, version string) string {
+	return strings.NewReplacer(
+		"{artifactBase}", t.Base,
+		"{path}", filename,
+		"{namespace}", namespace,
+		"{type}", typeName,
+		"{version}", version,
+		"{filename}", path.Base(filename),
+	).Replace(t.Template)
+}
+
+// ObjectOf returns the name of the file in the bucket referred to by the url, or false if the
+// url does not match the template, or the template does not refer to files by their path.
+func (t *ArtifactURLTemplate) ObjectOf(url string) (string, bool) {
+	if !t.HasPath() {
+		return "", false
+	}
+	prefix := t.Prefix()
+	if !strings.HasPrefix(url, prefix) || url == prefix {
+		return "", false
+	}
+	return strings.TrimPrefix(url, prefix), true
+}
+
+// HasPath returns true if the template refers to files by their path in the bucket only, so that
+// the path can be taken from the url.
+func (t *ArtifactURLTemplate) HasPath() bool {
+	return strings.HasSuffix(t.Template, "{path}") &&
+		t.Prefix()+"{path}" == strings.Replace(t.Template, "{artifactBase}", t.Base, 1)
+}
+
+// Prefix returns the start of all urls of the template, up to the first placeholder other than
+// {artifactBase}.
+func (t *ArtifactURLTemplate) Prefix() string {
+	template := strings.Replace(t.Template, "{artifactBase}", t.Base, 1)
+	if index := strings.Index(template, "{"); index >= 0 {
+		return template[:index]
+	}
+	return template
+}
@@ -0,0 +1,33 @@
+package versions
+
+import "testing"
+
+func TestArtifactURLTemplate(t *testing.T) {
+	template, err := NewArtifactURLTemplate("{artifactBase}/{namespace}/{type}/{version}/{filename}", "https://artifacts.example.com/terraform/")
+	if err != nil {
+		t.Fatalf("failed to create template, %s", err)
+	}
+	url := template.URL("binaries/terraform-provider-mollie_1.0.0_linux_amd64.zip", "mollie", "mollie", "1.0.0")
+	if url != "https://artifacts.example.com/terraform/mollie/mollie/1.0.0/terraform-provider-mollie_1.0.0_linux_amd64.zip" {
+		t.Errorf("unexpected url %s", url)
+	}
+	if _, ok := template.ObjectOf(url); ok {
+		t.Errorf("expected no object for a template without {path}")
+	}
+
+	options := &ReleaseOptions{Scheme: DefaultNamingScheme(), Namespace: "mollie", ArtifactURL: template, Protocols: []string{"5.0"}}
+	shasums := map[string]string{"binaries/terraform-provider-mollie_1.0.0_linux_amd64.zip": "a2c5881ea67e1c397cb26c6162d81829e058d5a993801bcb69df9982412d27e9"}
+	metadata, err := MakeFromFileName(options, "binaries/terraform-provider-mollie_1.0.0_linux_amd64.zip", shasums)
+	if err != nil {
+		t.Fatalf("unexpected error, %s", err)
+	}
+	if metadata.DownloadURL != url || metadata.ShasumsURL != "https://artifacts.example.com/terraform/mollie/mollie/1.0.0/terraform-provider-mollie_1.0.0_SHA256SUMS" {
+		t.Errorf("unexpected urls %s, %s", metadata.DownloadURL, metadata.ShasumsURL)
+	}
+
+	for _, invalid := range []string{"{artifactBase}/{os}/{filename}", "{artifactBase}/{namespace}"} {
+		if _, err = NewArtifactURLTemplate(invalid, ""); err == nil {
+			t.Errorf("expected %s to be invalid", invalid)
+		}
+	}
+}
@@ -1,15 +1,12 @@
 package versions
 
 import (
-	"cloud.google.com/go/storage"
-	"context"
 	"fmt"
 	"github.com/mollie/tf-provider-registry-api-generator/signing_key"
-	"google.golang.org/api/iterator"
-	"log"
+	log "github.com/sirupsen/logrus"
 	"path"
 	"reflect"
-	"regexp"
+	"sort"
 	"strings"
 )
 
@@ -33,14 +30,56 @@ type BinaryMetaData struct {
 	SigningKeys         struct {
 		GpgPublicKeys []GpgSigningKey `json:"gpg_public_keys"`
 	} `json:"signing_keys"`
-	Version  string `json:"-"`
-	TypeName string `json:"-"`
+	Version   string `json:"-"`
+	TypeName  string `json:"-"`
+	Namespace string `json:"-"`
 }
 
+// ReleaseOptions describes how the binary metadata is created from the release files.
+type ReleaseOptions struct {
+	Scheme         *NamingScheme
+	PrefixTemplate *PrefixTemplate
+	Namespace      string
+	BaseURL        string
+	ArtifactURL    *ArtifactURLTemplate
+	Protocols      []string
+}
+
+// artifactURL returns the download url of the release file in the bucket. Without an artifact
+// url template, the file is served from the base url.
+func (o *ReleaseOptions) artifactURL(filename string, m *BinaryMetaData) string {
+	if o.ArtifactURL == nil {
+		return fmt.Sprintf("%s/%s", o.BaseURL, filename)
+	}
+	return o.ArtifactURL.URL(filename, m.Namespace, m.TypeName, m.Version)
+}
+
+// Parse returns the namespace, type, version and platform of the archive, or nil if the file
+// is not a provider archive. The namespace is taken from the prefix template, if any.
+func (o *ReleaseOptions) Parse(filename string) *ReleaseFile {
+	release := o.Scheme.ParseArchive(filename)
+	if release == nil {
+		return nil
+	}
+	release.Namespace = o.namespaceOf(filename)
+	return release
+}
+
+func (o *ReleaseOptions) namespaceOf(filename string) string {
+	if o.PrefixTemplate != nil && o.PrefixTemplate.HasNamespace() {
+		if location := o.PrefixTemplate.Parse(filename); location != nil {
+			return location.Namespace
+		}
+	}
+	return o.Namespace
+}
+
+// Equals returns true if all serialized fields of the binaries are equal.
 func (l *BinaryMetaData) Equals(o *BinaryMetaData) bool {
 	result := l.Os == o.Os &&
 		l.Arch == o.Arch &&
 		l.Filename == o.Filename &&
+		l.DownloadURL == o.DownloadURL &&
 		l.ShasumsURL == o.ShasumsURL &&
 		l.ShasumsSignatureURL == o.ShasumsSignatureURL &&
 		l.Shasum == o.Shasum
@@ -55,10 +94,11 @@ type BinaryMetaDataList []BinaryMetaData
 func (l BinaryMetaDataList) ExtractVersions() map[string]*ProviderVersions {
 	result := make(map[string]*ProviderVersions)
 	for _, meta := range l {
-		versions, ok := result[meta.TypeName]
+		name := path.Join(meta.Namespace, meta.TypeName)
+		versions, ok := result[name]
 		if !ok {
 			versions = &ProviderVersions{}
-			result[meta.TypeName] = versions
+			result[name] = versions
 		}
 		versions.Add(&meta)
 	}
@@ -69,97 +109,158 @@ func (m *BinaryMetaData) Platform() Platform {
 	return Platform{Os: m.Os, Arch: m.Arch}
 }
 
-var (
-	releaseName = regexp.MustCompile(`(terraform-provider-)(?P<type>[^_]*)_(?P<version>[0-9]+\.[0-9]+\.[0-9]+)_((SHA256SUMS.*|((?P<os>[^_]*)_(?P<arch>[^.]*)(\.zip))))`)
-
-	binaryNameExpression = regexp.MustCompile(`(terraform-provider-)(?P<type>[^_]*)_(?P<version>[0-9]+\.[0-9]+\.[0-9]+)_(?P<os>[^_]*)_(?P<arch>[^.]*)(\.zip)`)
-	subExpressionNames   = binaryNameExpression.SubexpNames()
-)
+// ValidatePlatforms checks that the platforms of all binaries are known to Go and, if an
+// allow-list is specified, are allowed.
+func (l BinaryMetaDataList) ValidatePlatforms(allowed PlatformList) error {
+	messages := make([]string, 0)
+	for _, meta := range l {
+		platform := meta.Platform()
+		if err := platform.Validate(); err != nil {
+			messages = append(messages, fmt.Sprintf("%s: %s", meta.Filename, err))
+		} else if len(allowed) > 0 && !allowed.Contains(platform) {
+			messages = append(messages, fmt.Sprintf("%s: platform %s is not allowed", meta.Filename, platform))
+		}
+	}
+	if len(messages) > 0 {
+		return fmt.Errorf("invalid platforms:\n  %s", strings.Join(messages, "\n  "))
+	}
+	return nil
+}
 
-func MakeFromFileName(baseURL string, filename string, shasums map[string]string, protocols []string) *BinaryMetaData {
+// MakeFromFileName returns the binary metadata of the archive, or nil if the file is not an
+// archive. The shasums are keyed by the path of the archive. It returns an error if the
+// SHA256SUMS contain no shasum of the archive.
+func MakeFromFileName(options *ReleaseOptions, filename string, shasums map[string]string) (*BinaryMetaData, error) {
 	dirname := path.Dir(filename)
 	base := path.Base(filename)
-	matches := binaryNameExpression.FindStringSubmatch(base)
-	if matches == nil {
-		return nil
+	release := options.Parse(filename)
+	if release == nil {
+		return nil, nil
 	}
-	metadata := BinaryMetaData{}
-	for i, name := range subExpressionNames {
-		switch name {
-		case "type":
-			metadata.TypeName = matches[i]
-		case "version":
-			metadata.Version = matches[i]
-		case "os":
-			metadata.Os = matches[i]
-		case "arch":
-			metadata.Arch = matches[i]
-		default:
-			// ignore
-		}
+	metadata := BinaryMetaData{
+		Namespace: release.Namespace,
+		TypeName:  release.TypeName,
+		Version:   release.Version,
+		Os:        release.Os,
+		Arch:      release.Arch,
 	}
 
-	url := fmt.Sprintf("%s/%s", baseURL, dirname)
-	metadata.DownloadURL = fmt.Sprintf("%s/%s", baseURL, filename)
-	metadata.Protocols = protocols
-	metadata.ShasumsURL = fmt.Sprintf("%s/terraform-provider-%s_%s_SHA256SUMS",
-		url, metadata.TypeName, metadata.Version)
-	metadata.ShasumsSignatureURL = fmt.Sprintf("%s/terraform-provider-%s_%s_SHA256SUMS.sig",
-		url, metadata.TypeName, metadata.Version)
+	metadata.DownloadURL = options.artifactURL(filename, &metadata)
+	metadata.Protocols = options.Protocols
+	metadata.ShasumsURL = options.artifactURL(
+		path.Join(dirname, options.Scheme.ShasumsFileName(metadata.TypeName, metadata.Version)), &metadata)
+	metadata.ShasumsSignatureURL = options.artifactURL(
+		path.Join(dirname, options.Scheme.SignatureFileName(metadata.TypeName, metadata.Version)), &metadata)
 	metadata.Filename = base
 
 	var ok bool
-	if metadata.Shasum, ok = shasums[base]; !ok {
-		log.Fatalf("ERROR: no shasum found found %s", base)
+	if metadata.Shasum, ok = shasums[filename]; !ok {
+		return nil, fmt.Errorf("no shasum found for %s", filename)
 	}
 
-	return &metadata
+	return &metadata, nil
 }
 
-func CreateFromFileList(files []string, baseURL string, signingKey signing_key.PGPSigningKey, shasums map[string]string, protocols []string) BinaryMetaDataList {
+// LogFields returns the fields identifying the binary in log messages.
+func (m *BinaryMetaData) LogFields() log.Fields {
+	return log.Fields{
+		"namespace": m.Namespace,
+		"type":      m.TypeName,
+		"version":   m.Version,
+		"os":        m.Os,
+		"arch":      m.Arch,
+	}
+}
+
+// CreateFromFileList returns the binary metadata of the archives in the files. The shasums and
+// signatures are keyed by the path of the file, so that releases in different directories may
+// contain files with the same name. It returns an error if an archive has no shasum, or a
+// SHA256SUMS is not signed by one of the signing keys.
+func CreateFromFileList(options *ReleaseOptions, files []string, signingKeys []signing_key.PGPSigningKey, signatures map[string][]byte, shasums map[string]string) (BinaryMetaDataList, error) {
 
 	result := make(BinaryMetaDataList, 0, len(files))
+	archives := make(map[string]bool, len(files))
+	releaseKeys := make(map[string][]signing_key.PGPSigningKey)
 
 	for _, f := range files {
-		metadata := MakeFromFileName(baseURL, f, shasums, protocols)
-		if metadata != nil {
-			result = append(result, *metadata)
+		metadata, err := MakeFromFileName(options, f, shasums)
+		if err != nil {
+			return nil, err
+		}
+		if metadata == nil {
+			continue
 		}
+		signatureFile := path.Join(path.Dir(f), options.Scheme.SignatureFileName(metadata.TypeName, metadata.Version))
+		keys, ok := releaseKeys[signatureFile]
+		if !ok {
+			if keys, err = signingKeysOfRelease(signatureFile, signingKeys, signatures); err != nil {
+				return nil, err
+			}
+			releaseKeys[signatureFile] = keys
+		}
+		metadata.SetPGPSigningKeys(keys)
+		result = append(result, *metadata)
+		archives[f] = true
 	}
 
-	result.SetPGPSigningKey(signingKey)
+	unmatched := make([]string, 0)
+	for filename := range shasums {
+		if !archives[filename] {
+			unmatched = append(unmatched, filename)
+		}
+	}
+	sort.Strings(unmatched)
+	for _, filename := range unmatched {
+		log.WithField("filename", filename).Warn("SHA256SUMS entry does not match any provider archive")
+	}
 
-	return result
+	return result, nil
 }
 
-func (l BinaryMetaDataList) SetPGPSigningKey(signingKey signing_key.PGPSigningKey) {
-	for i := range l {
-		(l)[i].SigningKeys.GpgPublicKeys = []GpgSigningKey{{KeyID: signingKey.KeyID,
-			ASCIIArmor: signingKey.ASCIIArmor}}
+// signingKeysOfRelease returns the key which signed the SHA256SUMS of the release. If the
+// release has no signature, all keys are returned.
+func signingKeysOfRelease(signatureFile string, signingKeys []signing_key.PGPSigningKey, signatures map[string][]byte) ([]signing_key.PGPSigningKey, error) {
+	signature, ok := signatures[signatureFile]
+	if !ok {
+		log.WithField("filename", signatureFile).Warn("no signature found, listing all signing keys")
+		return signingKeys, nil
+	}
+	key, err := signing_key.FindSigningKey(signingKeys, signature)
+	if err != nil {
+		return nil, fmt.Errorf("invalid signature %s, %s", signatureFile, err)
+	}
+	return []signing_key.PGPSigningKey{*key}, nil
+}
+
+func (m *BinaryMetaData) SetPGPSigningKeys(signingKeys []signing_key.PGPSigningKey) {
+	m.SigningKeys.GpgPublicKeys = make([]GpgSigningKey, 0, len(signingKeys))
+	for _, signingKey := range signingKeys {
+		key := GpgSigningKey{
+			KeyID:          signingKey.KeyID,
+			ASCIIArmor:     signingKey.ASCIIArmor,
+			TrustSignature: signingKey.TrustSignature,
+			Source:         signingKey.Source,
+		}
+		if signingKey.SourceURL != "" {
+			sourceURL := signingKey.SourceURL
+			key.SourceURL = &sourceURL
+		}
+		m.SigningKeys.GpgPublicKeys = append(m.SigningKeys.GpgPublicKeys, key)
 	}
 }
 
-func LoadFromBucket(bucket *storage.BucketHandle, prefix string) (filenames []string) {
+// SelectReleaseFiles returns the archives, SHA256SUMS and signatures from the object names.
+// With a prefix template, only files in directories matching the template are selected.
+func SelectReleaseFiles(names []string, options *ReleaseOptions) (filenames []string) {
 
 	filenames = make([]string, 0)
 
-	q := storage.Query{Prefix: fmt.Sprintf("%s/", strings.Trim(prefix, "/"))}
-	it := bucket.Objects(context.Background(), &q)
-	for {
-		attrs, err := it.Next()
-		if err == iterator.Done {
-			break
-		}
-		if err != nil {
-			log.Fatalf("list objects from bucket failed, %s", err)
-		}
-		matches := releaseName.FindStringSubmatch(attrs.Name)
-		if matches != nil {
-			filenames = append(filenames, attrs.Name)
+	for _, name := range names {
+		if options.Scheme.IsReleaseFile(name) && (options.PrefixTemplate == nil || options.PrefixTemplate.Parse(name) != nil) {
+			filenames = append(filenames, name)
 		} else {
-			log.Printf("INFO: skipping %s", attrs.Name)
+			log.WithField("path", name).Debug("skipping, not a release file")
 		}
-
 	}
 	return filenames
-}
\ No newline at end of file
+}
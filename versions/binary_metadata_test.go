@@ -0,0 +1,177 @@
+package versions
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/mollie/tf-provider-registry-api-generator/signing_key"
+	"golang.org/x/crypto/openpgp"
+	"golang.org/x/crypto/openpgp/armor"
+	"golang.org/x/crypto/openpgp/packet"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestBinaryMetaData_Equals(t *testing.T) {
+	sourceURL := "https://www.example.com/security/pgp-keys"
+	newBinary := func() *BinaryMetaData {
+		b := &BinaryMetaData{
+			Protocols:           []string{"5.0"},
+			Os:                  "linux",
+			Arch:                "amd64",
+			Filename:            "terraform-provider-mollie_1.0.0_linux_amd64.zip",
+			DownloadURL:         "https://registry.example.com/terraform-provider-mollie_1.0.0_linux_amd64.zip",
+			ShasumsURL:          "https://registry.example.com/terraform-provider-mollie_1.0.0_SHA256SUMS",
+			ShasumsSignatureURL: "https://registry.example.com/terraform-provider-mollie_1.0.0_SHA256SUMS.sig",
+			Shasum:              "a2c5881ea67e1c397cb26c6162d81829e058d5a993801bcb69df9982412d27e9",
+		}
+		b.SigningKeys.GpgPublicKeys = []GpgSigningKey{{KeyID: "B64689ABE6ED9C52", SourceURL: &sourceURL}}
+		return b
+	}
+	if !newBinary().Equals(newBinary()) {
+		t.Fatalf("expected equal binaries")
+	}
+
+	// every serialized field must be compared, so that a change of any field is published.
+	binaryType := reflect.TypeOf(BinaryMetaData{})
+	for i := 0; i < binaryType.NumField(); i++ {
+		field := binaryType.Field(i)
+		if field.Tag.Get("json") == "-" {
+			continue
+		}
+		other := newBinary()
+		value := reflect.ValueOf(other).Elem().Field(i)
+		switch value.Kind() {
+		case reflect.String:
+			value.SetString(value.String() + "x")
+		case reflect.Slice:
+			value.Set(reflect.Append(value, reflect.ValueOf("4.0")))
+		case reflect.Struct:
+			other.SigningKeys.GpgPublicKeys[0].KeyID = "51229EAFE0F52100"
+		default:
+			t.Fatalf("unsupported field %s", field.Name)
+		}
+		if newBinary().Equals(other) {
+			a, _ := json.Marshal(other)
+			t.Errorf("expected a change of %s to be detected, %s", field.Name, a)
+		}
+	}
+
+	other := newBinary()
+	otherSourceURL := "https://keys.example.com"
+	other.SigningKeys.GpgPublicKeys[0].SourceURL = &otherSourceURL
+	if newBinary().Equals(other) {
+		t.Errorf("expected a change of the source url to be detected")
+	}
+}
+
+func TestBinaryMetaDataList_ValidatePlatforms(t *testing.T) {
+	allowed := PlatformList{{Os: "linux", Arch: "amd64"}, {Os: "darwin", Arch: "arm64"}}
+	tests := []struct {
+		name    string
+		os      string
+		arch    string
+		allowed PlatformList
+		wantErr bool
+	}{
+		{"known", "linux", "arm64", nil, false},
+		{"unknown_os", "plan10", "amd64", nil, true},
+		{"unknown_arch", "linux", "x86", nil, true},
+		{"allowed", "darwin", "arm64", allowed, false},
+		{"not_allowed", "linux", "arm64", allowed, true},
+		{"unknown_and_not_allowed", "plan10", "amd64", allowed, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			binaries := BinaryMetaDataList{{Filename: "terraform-provider-mollie_1.0.0_" + tt.os + "_" + tt.arch + ".zip", Os: tt.os, Arch: tt.arch}}
+			if err := binaries.ValidatePlatforms(tt.allowed); (err != nil) != tt.wantErr {
+				t.Errorf("expected error %v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestCreateFromFileList_errors(t *testing.T) {
+	options := &ReleaseOptions{Scheme: DefaultNamingScheme(), BaseURL: "https://registry.example.com", Protocols: []string{"5.0"}}
+	files := []string{
+		"binaries/terraform-provider-mollie_1.0.0_linux_amd64.zip",
+		"binaries/terraform-provider-mollie_1.0.0_SHA256SUMS",
+		"binaries/terraform-provider-mollie_1.0.0_SHA256SUMS.sig",
+	}
+	shasums := map[string]string{"binaries/terraform-provider-mollie_1.0.0_linux_amd64.zip": "a2c5881ea67e1c397cb26c6162d81829e058d5a993801bcb69df9982412d27e9"}
+
+	if _, err := CreateFromFileList(options, files, nil, nil, map[string]string{}); err == nil {
+		t.Errorf("expected an error for an archive without shasum")
+	}
+	signatures := map[string][]byte{"binaries/terraform-provider-mollie_1.0.0_SHA256SUMS.sig": []byte("invalid")}
+	if _, err := CreateFromFileList(options, files, nil, signatures, shasums); err == nil {
+		t.Errorf("expected an error for an invalid signature")
+	}
+	if binaries, err := CreateFromFileList(options, files, nil, nil, shasums); err != nil || len(binaries) != 1 {
+		t.Errorf("expected a single binary, got %v, %v", binaries, err)
+	}
+}
+
+func newSigningKey(t *testing.T, name string) *signing_key.PrivateSigningKey {
+	entity, err := openpgp.NewEntity(name, "", name+"@example.com", &packet.Config{RSABits: 1024})
+	if err != nil {
+		t.Fatalf("failed to generate key, %s", err)
+	}
+	var armored bytes.Buffer
+	w, _ := armor.Encode(&armored, openpgp.PrivateKeyType, nil)
+	if err = entity.SerializePrivate(w, nil); err != nil {
+		t.Fatalf("failed to serialize key, %s", err)
+	}
+	w.Close()
+	key, err := signing_key.ReadPrivateSigningKey(armored.String(), "")
+	if err != nil {
+		t.Fatalf("failed to read key, %s", err)
+	}
+	return key
+}
+
+func TestCreateFromFileList_namespaces(t *testing.T) {
+	template, _ := NewPrefixTemplate("binaries/{namespace}/{type}/{version}")
+	options := &ReleaseOptions{Scheme: DefaultNamingScheme(), PrefixTemplate: template, BaseURL: "https://registry.example.com", Protocols: []string{"5.0"}}
+	shasums := make(map[string]string)
+	signatures := make(map[string][]byte)
+	signingKeys := make([]signing_key.PGPSigningKey, 0)
+	files := make([]string, 0)
+	for i, namespace := range []string{"mollie", "other"} {
+		directory := "binaries/" + namespace + "/mollie/1.0.0/"
+		content := "a2c5881ea67e1c397cb26c6162d81829e058d5a993801bcb69df9982412d27e" + string(rune('0'+i)) + "  terraform-provider-mollie_1.0.0_linux_amd64.zip\n"
+		if err := ParseShasumsOf(bytes.NewReader([]byte(content)), directory, shasums); err != nil {
+			t.Fatalf("failed to parse shasums, %s", err)
+		}
+		key := newSigningKey(t, namespace)
+		publicKey, err := key.PublicKey()
+		if err != nil {
+			t.Fatalf("failed to export public key, %s", err)
+		}
+		signingKeys = append(signingKeys, publicKey)
+		if signatures[directory+"terraform-provider-mollie_1.0.0_SHA256SUMS.sig"], err = key.Sign([]byte(content)); err != nil {
+			t.Fatalf("failed to sign, %s", err)
+		}
+		files = append(files,
+			directory+"terraform-provider-mollie_1.0.0_linux_amd64.zip",
+			directory+"terraform-provider-mollie_1.0.0_SHA256SUMS",
+			directory+"terraform-provider-mollie_1.0.0_SHA256SUMS.sig")
+	}
+
+	binaries, err := CreateFromFileList(options, files, signingKeys, signatures, shasums)
+	if err != nil {
+		t.Fatalf("unexpected error, %s", err)
+	}
+	if len(binaries) != 2 {
+		t.Fatalf("expected 2 binaries, got %d", len(binaries))
+	}
+	for i, binary := range binaries {
+		if !strings.HasSuffix(binary.Shasum, string(rune('0'+i))) {
+			t.Errorf("expected the shasum of %s from its own SHA256SUMS, got %s", binary.Namespace, binary.Shasum)
+		}
+		keys := binary.SigningKeys.GpgPublicKeys
+		if len(keys) != 1 || keys[0].KeyID != signingKeys[i].KeyID {
+			t.Errorf("expected %s to be signed by its own key, got %v", binary.Namespace, keys)
+		}
+	}
+}
@@ -0,0 +1,208 @@
+package versions
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+)
+
+const (
+	DefaultArchiveTemplate   = "terraform-provider-{type}_{version}_{os}_{arch}.zip"
+	DefaultShasumsTemplate   = "terraform-provider-{type}_{version}_SHA256SUMS"
+	DefaultSignatureTemplate = "terraform-provider-{type}_{version}_SHA256SUMS.sig"
+)
+
+// DefaultPlatformMapping maps the os and arch names commonly used in release file names
+// to the names used by Terraform.
+var DefaultPlatformMapping = map[string]string{
+	"Darwin":  "darwin",
+	"Linux":   "linux",
+	"Windows": "windows",
+	"Freebsd": "freebsd",
+	"FreeBSD": "freebsd",
+	"Openbsd": "openbsd",
+	"OpenBSD": "openbsd",
+	"Solaris": "solaris",
+	"x86_64":  "amd64",
+	"i386":    "386",
+	"aarch64": "arm64",
+}
+
+var (
+	placeholderExpression = regexp.MustCompile(`{(namespace|type|version|os|arch)}`)
+	placeholderPatterns   = map[string]string{
+		"namespace": `(?P<namespace>[^/]+)`,
+		"type":      `(?P<type>[^/]+?)`,
+		"version":   `(?P<version>[0-9]+\.[0-9]+\.[0-9]+(?:-[0-9A-Za-z.-]+)?(?:\+[0-9A-Za-z.-]+)?)`,
+		"os":        `(?P<os>[^_./]+)`,
+		"arch":      `(?P<arch>[^./]+?)`,
+	}
+)
+
+// NamingScheme describes the file names of the archives, SHA256SUMS and signatures of a
+// provider release. The names are specified as templates with the placeholders {type},
+// {version}, {os} and {arch}. The archive name may also be specified as a regular expression
+// with the named groups type, version, os and arch.
+//
+// The type is matched up to the first occurrence of the version, so a type name containing
+// underscores is parsed as a whole and can be reported as invalid by ValidateTypeName.
+type NamingScheme struct {
+	ArchiveTemplate   string
+	ShasumsTemplate   string
+	SignatureTemplate string
+	PlatformMapping   map[string]string
+	archive           *regexp.Regexp
+	shasums           *regexp.Regexp
+	signature         *regexp.Regexp
+}
+
+// ReleaseFile is the namespace, type, version and platform parsed from the name of a release file.
+type ReleaseFile struct {
+	Namespace string
+	TypeName  string
+	Version   string
+	Os        string
+	Arch      string
+}
+
+// NewNamingScheme creates a naming scheme from the templates. The platform mapping is added
+// to the default platform mapping.
+func NewNamingScheme(archive string, shasums string, signature string, platformMapping map[string]string) (*NamingScheme, error) {
+	var err error
+	result := NamingScheme{
+		ArchiveTemplate:   archive,
+		ShasumsTemplate:   shasums,
+		SignatureTemplate: signature,
+		PlatformMapping:   make(map[string]string),
+	}
+	for k, v := range DefaultPlatformMapping {
+		result.PlatformMapping[k] = v
+	}
+	for k, v := range platformMapping {
+		result.PlatformMapping[k] = v
+	}
+
+	if strings.HasPrefix(archive, "^") || strings.Contains(archive, "(?P<") {
+		result.archive, err = regexp.Compile(archive)
+		if err != nil {
+			return nil, fmt.Errorf("invalid archive expression %s, %s", archive, err)
+		}
+	} else {
+		result.archive = templateToRegexp(archive)
+	}
+	if err = requireGroups(result.archive, "archive", "type", "version", "os", "arch"); err != nil {
+		return nil, err
+	}
+
+	result.shasums = templateToRegexp(shasums)
+	if err = requireGroups(result.shasums, "SHA256SUMS", "type", "version"); err != nil {
+		return nil, err
+	}
+	result.signature = templateToRegexp(signature)
+	if err = requireGroups(result.signature, "signature", "type", "version"); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// DefaultNamingScheme returns the naming scheme used by the goreleaser terraform provider template.
+func DefaultNamingScheme() *NamingScheme {
+	result, err := NewNamingScheme(DefaultArchiveTemplate, DefaultShasumsTemplate, DefaultSignatureTemplate, nil)
+	if err != nil {
+		panic(err)
+	}
+	return result
+}
+
+func templateToRegexp(template string) *regexp.Regexp {
+	var pattern strings.Builder
+	pattern.WriteString("^")
+	last := 0
+	for _, match := range placeholderExpression.FindAllStringSubmatchIndex(template, -1) {
+		pattern.WriteString(regexp.QuoteMeta(template[last:match[0]]))
+		pattern.WriteString(placeholderPatterns[template[match[2]:match[3]]])
+		last = match[1]
+	}
+	pattern.WriteString(regexp.QuoteMeta(template[last:]))
+	pattern.WriteString("$")
+	return regexp.MustCompile(pattern.String())
+}
+
+func requireGroups(expression *regexp.Regexp, kind string, groups ...string) error {
+	for _, group := range groups {
+		if expression.SubexpIndex(group) < 0 {
+			return fmt.Errorf("the %s name %s has no %s", kind, expression, group)
+		}
+	}
+	return nil
+}
+
+func (n *NamingScheme) parse(expression *regexp.Regexp, filename string) *ReleaseFile {
+	matches := expression.FindStringSubmatch(path.Base(filename))
+	if matches == nil {
+		return nil
+	}
+	result := ReleaseFile{}
+	for i, name := range expression.SubexpNames() {
+		switch name {
+		case "namespace":
+			result.Namespace = matches[i]
+		case "type":
+			result.TypeName = matches[i]
+		case "version":
+			result.Version = matches[i]
+		case "os":
+			result.Os = n.mapPlatform(matches[i])
+		case "arch":
+			result.Arch = n.mapPlatform(matches[i])
+		default:
+			// ignore
+		}
+	}
+	return &result
+}
+
+func (n *NamingScheme) mapPlatform(name string) string {
+	if mapped, ok := n.PlatformMapping[name]; ok {
+		return mapped
+	}
+	return name
+}
+
+// ParseArchive returns the type, version and platform of a provider archive, or nil if
+// the file is not a provider archive.
+func (n *NamingScheme) ParseArchive(filename string) *ReleaseFile {
+	return n.parse(n.archive, filename)
+}
+
+// ParseShasums returns the type and version of a SHA256SUMS file, or nil if the file is not
+// a SHA256SUMS file.
+func (n *NamingScheme) ParseShasums(filename string) *ReleaseFile {
+	return n.parse(n.shasums, filename)
+}
+
+// ParseSignature returns the type and version of a SHA256SUMS signature file, or nil if the
+// file is not a signature.
+func (n *NamingScheme) ParseSignature(filename string) *ReleaseFile {
+	return n.parse(n.signature, filename)
+}
+
+// IsReleaseFile returns true if the file is an archive, a SHA256SUMS or a signature.
+func (n *NamingScheme) IsReleaseFile(filename string) bool {
+	return n.ParseArchive(filename) != nil || n.ParseShasums(filename) != nil || n.ParseSignature(filename) != nil
+}
+
+// ShasumsFileName returns the name of the SHA256SUMS file of the release.
+func (n *NamingScheme) ShasumsFileName(typeName string, version string) string {
+	return render(n.ShasumsTemplate, typeName, version)
+}
+
+// SignatureFileName returns the name of the SHA256SUMS signature file of the release.
+func (n *NamingScheme) SignatureFileName(typeName string, version string) string {
+	return render(n.SignatureTemplate, typeName, version)
+}
+
+func render(template string, typeName string, version string) string {
+	return strings.NewReplacer("{type}", typeName, "{version}", version).Replace(template)
+}
@@ -0,0 +1,93 @@
+package versions
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestNamingScheme_ParseArchive(t *testing.T) {
+	tests := []struct {
+		name     string
+		archive  string
+		mapping  map[string]string
+		filename string
+		want     *ReleaseFile
+	}{
+		{"default",
+			DefaultArchiveTemplate, nil,
+			"binaries/jianyuan/terraform-provider-sentry/v0.6.0/terraform-provider-sentry_0.6.0_darwin_amd64.zip",
+			&ReleaseFile{TypeName: "sentry", Version: "0.6.0", Os: "darwin", Arch: "amd64"},
+		},
+		{"default_shasums_is_no_archive",
+			DefaultArchiveTemplate, nil,
+			"binaries/jianyuan/terraform-provider-sentry/v0.6.0/terraform-provider-sentry_0.6.0_SHA256SUMS",
+			nil,
+		},
+		{"version_prefix",
+			"terraform-provider-{type}_v{version}_{os}_{arch}.zip", nil,
+			"terraform-provider-sentry_v0.6.0_linux_arm64.zip",
+			&ReleaseFile{TypeName: "sentry", Version: "0.6.0", Os: "linux", Arch: "arm64"},
+		},
+		{"default_platform_mapping",
+			"terraform-provider-{type}_{version}_{os}_{arch}.zip", nil,
+			"terraform-provider-sentry_0.6.0_Linux_x86_64.zip",
+			&ReleaseFile{TypeName: "sentry", Version: "0.6.0", Os: "linux", Arch: "amd64"},
+		},
+		{"custom_platform_mapping",
+			"terraform-provider-{type}_{version}_{os}-{arch}.zip", map[string]string{"macOS": "darwin", "x64": "amd64"},
+			"terraform-provider-sentry_0.6.0_macOS-x64.zip",
+			&ReleaseFile{TypeName: "sentry", Version: "0.6.0", Os: "darwin", Arch: "amd64"},
+		},
+		{"regular_expression",
+			`^(?P<type>[a-z]+)-(?P<version>[0-9.]+)-(?P<os>[a-z]+)-(?P<arch>[a-z0-9]+)\.zip$`, nil,
+			"sentry-0.6.0-linux-amd64.zip",
+			&ReleaseFile{TypeName: "sentry", Version: "0.6.0", Os: "linux", Arch: "amd64"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			scheme, err := NewNamingScheme(tt.archive, DefaultShasumsTemplate, DefaultSignatureTemplate, tt.mapping)
+			if err != nil {
+				t.Fatalf("failed to create naming scheme, %s", err)
+			}
+			if got := scheme.ParseArchive(tt.filename); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestNewNamingScheme(t *testing.T) {
+	if _, err := NewNamingScheme("terraform-provider-{type}_{os}_{arch}.zip", DefaultShasumsTemplate, DefaultSignatureTemplate, nil); err == nil {
+		t.Errorf("expected an error for an archive template without {version}")
+	}
+
+	scheme := DefaultNamingScheme()
+	if got := scheme.ShasumsFileName("sentry", "0.6.0"); got != "terraform-provider-sentry_0.6.0_SHA256SUMS" {
+		t.Errorf("unexpected SHA256SUMS file name %s", got)
+	}
+	if got := scheme.SignatureFileName("sentry", "0.6.0"); got != "terraform-provider-sentry_0.6.0_SHA256SUMS.sig" {
+		t.Errorf("unexpected signature file name %s", got)
+	}
+	if release := scheme.ParseSignature("terraform-provider-sentry_0.6.0_SHA256SUMS.sig"); release == nil || release.TypeName != "sentry" {
+		t.Errorf("expected signature to be parsed, got %v", release)
+	}
+}
+
+func TestNamingScheme_ParseArchiveTypeWithUnderscores(t *testing.T) {
+	scheme := DefaultNamingScheme()
+	got := scheme.ParseArchive("terraform-provider-my_thing_1.0.0_linux_amd64.zip")
+	want := &ReleaseFile{TypeName: "my_thing", Version: "1.0.0", Os: "linux", Arch: "amd64"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+}
+
+func TestNamingScheme_ParseArchivePrerelease(t *testing.T) {
+	scheme := DefaultNamingScheme()
+	got := scheme.ParseArchive("terraform-provider-mollie_1.0.0-beta1_linux_amd64.zip")
+	want := &ReleaseFile{TypeName: "mollie", Version: "1.0.0-beta1", Os: "linux", Arch: "amd64"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+}
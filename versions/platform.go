@@ -1,5 +1,11 @@
 package versions
 
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
 type Platform struct {
 	Os   string `json:"os"`
 	Arch string `json:"arch"`
@@ -16,3 +22,69 @@ func (a PlatformList) Swap(i, j int) { a[i], a[j] = a[j], a[i] }
 func (a PlatformList) Less(i, j int) bool {
 	return a[i].Os < a[j].Os || a[i].Os == a[j].Os && a[i].Arch < a[j].Arch
 }
+
+// KnownOperatingSystems are the GOOS values known to Go.
+var KnownOperatingSystems = map[string]bool{
+	"aix": true, "android": true, "darwin": true, "dragonfly": true, "freebsd": true,
+	"illumos": true, "ios": true, "js": true, "linux": true, "netbsd": true,
+	"openbsd": true, "plan9": true, "solaris": true, "wasip1": true, "windows": true,
+}
+
+// KnownArchitectures are the GOARCH values known to Go.
+var KnownArchitectures = map[string]bool{
+	"386": true, "amd64": true, "arm": true, "arm64": true, "loong64": true,
+	"mips": true, "mips64": true, "mips64le": true, "mipsle": true, "ppc64": true,
+	"ppc64le": true, "riscv64": true, "s390x": true, "wasm": true,
+}
+
+func (p Platform) String() string {
+	return p.Os + "_" + p.Arch
+}
+
+// Validate checks that the os and arch are known to Go.
+func (p Platform) Validate() error {
+	if !KnownOperatingSystems[p.Os] {
+		return fmt.Errorf("platform %s has an unknown os '%s'", p, p.Os)
+	}
+	if !KnownArchitectures[p.Arch] {
+		return fmt.Errorf("platform %s has an unknown arch '%s'", p, p.Arch)
+	}
+	return nil
+}
+
+// ParsePlatform parses and validates a platform in the form os_arch or os/arch.
+func ParsePlatform(platform string) (Platform, error) {
+	parts := strings.SplitN(strings.Replace(platform, "/", "_", 1), "_", 2)
+	if len(parts) != 2 {
+		return Platform{}, fmt.Errorf("%s is not a platform of the form os_arch", platform)
+	}
+	result := Platform{Os: parts[0], Arch: parts[1]}
+	return result, result.Validate()
+}
+
+// ParsePlatformList parses and validates a comma separated list of platforms.
+func ParsePlatformList(platforms string) (PlatformList, error) {
+	result := make(PlatformList, 0)
+	for _, p := range strings.Split(platforms, ",") {
+		if p = strings.TrimSpace(p); p == "" {
+			continue
+		}
+		platform, err := ParsePlatform(p)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, platform)
+	}
+	sort.Sort(result)
+	return result, nil
+}
+
+// Contains returns true if the platform is in the list.
+func (a PlatformList) Contains(platform Platform) bool {
+	for _, p := range a {
+		if p.Equals(&platform) {
+			return true
+		}
+	}
+	return false
+}
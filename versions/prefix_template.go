@@ -0,0 +1,86 @@
+package versions
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// PrefixTemplate describes the directory layout of the releases in the bucket, for example
+// binaries/{namespace}/terraform-provider-{type}/v{version}. The namespace of a release is
+// taken from its directory, and the type and version are cross-checked against the file names.
+type PrefixTemplate struct {
+	Template   string
+	expression *regexp.Regexp
+}
+
+// NewPrefixTemplate creates a prefix template with the placeholders {namespace}, {type} and
+// {version}.
+func NewPrefixTemplate(template string) (*PrefixTemplate, error) {
+	template = strings.Trim(template, "/")
+	if strings.Contains(template, "{os}") || strings.Contains(template, "{arch}") {
+		return nil, fmt.Errorf("the prefix template %s may only contain {namespace}, {type} and {version}", template)
+	}
+	return &PrefixTemplate{Template: template, expression: templateToRegexp(template)}, nil
+}
+
+// HasNamespace returns true if the namespace is taken from the directory.
+func (t *PrefixTemplate) HasNamespace() bool {
+	return strings.Contains(t.Template, "{namespace}")
+}
+
+// ListPrefix returns the prefix of all directories matching the template.
+func (t *PrefixTemplate) ListPrefix() string {
+	prefix := t.Template
+	if i := strings.Index(prefix, "{"); i >= 0 {
+		prefix = prefix[:i]
+	}
+	if i := strings.LastIndex(prefix, "/"); i >= 0 {
+		return prefix[:i+1]
+	}
+	return ""
+}
+
+// Directory returns the directory of the release of the provider version.
+func (t *PrefixTemplate) Directory(namespace string, typeName string, version string) string {
+	return strings.NewReplacer("{namespace}", namespace, "{type}", typeName, "{version}", version).Replace(t.Template)
+}
+
+// Parse returns the namespace, type and version of the directory of the file, or nil if
+// the directory does not match the template.
+func (t *PrefixTemplate) Parse(filename string) *ReleaseFile {
+	dirname := path.Dir(filename)
+	matches := t.expression.FindStringSubmatch(dirname)
+	if matches == nil {
+		return nil
+	}
+	result := ReleaseFile{}
+	for i, name := range t.expression.SubexpNames() {
+		switch name {
+		case "namespace":
+			result.Namespace = matches[i]
+		case "type":
+			result.TypeName = matches[i]
+		case "version":
+			result.Version = matches[i]
+		}
+	}
+	return &result
+}
+
+// Check returns an error if the type or version of the release file differs from the type
+// or version in its directory.
+func (t *PrefixTemplate) Check(filename string, release *ReleaseFile) error {
+	location := t.Parse(filename)
+	if location == nil {
+		return fmt.Errorf("the directory does not match %s", t.Template)
+	}
+	if location.TypeName != "" && location.TypeName != release.TypeName {
+		return fmt.Errorf("the type %s differs from the type %s in the directory", release.TypeName, location.TypeName)
+	}
+	if location.Version != "" && location.Version != release.Version {
+		return fmt.Errorf("the version %s differs from the version %s in the directory", release.Version, location.Version)
+	}
+	return nil
+}
@@ -0,0 +1,55 @@
+package versions
+
+import "testing"
+
+func TestPrefixTemplate(t *testing.T) {
+	template, err := NewPrefixTemplate("binaries/{namespace}/terraform-provider-{type}/v{version}/")
+	if err != nil {
+		t.Fatalf("failed to create prefix template, %s", err)
+	}
+	if prefix := template.ListPrefix(); prefix != "binaries/" {
+		t.Errorf("expected list prefix binaries/, got %s", prefix)
+	}
+	if directory := template.Directory("mollie", "mollie", "1.0.0"); directory != "binaries/mollie/terraform-provider-mollie/v1.0.0" {
+		t.Errorf("expected directory binaries/mollie/terraform-provider-mollie/v1.0.0, got %s", directory)
+	}
+
+	options := &ReleaseOptions{Scheme: DefaultNamingScheme(), PrefixTemplate: template, BaseURL: "https://registry.example.com", Protocols: []string{"5.0"}}
+	files := SelectReleaseFiles([]string{
+		"binaries/jianyuan/terraform-provider-sentry/v0.6.0/terraform-provider-sentry_0.6.0_darwin_amd64.zip",
+		"binaries/jianyuan/terraform-provider-sentry/v0.6.0/terraform-provider-sentry_0.6.0_SHA256SUMS",
+		"binaries/mollie/terraform-provider-mollie/v1.0.0/terraform-provider-mollie_1.0.0_linux_amd64.zip",
+		"binaries/mollie/terraform-provider-mollie/terraform-provider-mollie_1.0.0_linux_amd64.zip",
+	}, options)
+	if len(files) != 3 {
+		t.Fatalf("expected 3 files matching the template, got %v", files)
+	}
+
+	if release := options.Parse(files[2]); release == nil || release.Namespace != "mollie" {
+		t.Errorf("expected namespace mollie, got %v", release)
+	}
+	if err = ValidateReleaseFiles(options, files); err != nil {
+		t.Errorf("unexpected error, %s", err)
+	}
+
+	mismatch := []string{"binaries/mollie/terraform-provider-mollie/v1.0.1/terraform-provider-mollie_1.0.0_linux_amd64.zip"}
+	if err = ValidateReleaseFiles(options, mismatch); err == nil {
+		t.Errorf("expected an error for a version mismatch")
+	}
+
+	shasums := map[string]string{
+		"binaries/jianyuan/terraform-provider-sentry/v0.6.0/terraform-provider-sentry_0.6.0_darwin_amd64.zip": "a2c5881ea67e1c397cb26c6162d81829e058d5a993801bcb69df9982412d27e9",
+		"binaries/mollie/terraform-provider-mollie/v1.0.0/terraform-provider-mollie_1.0.0_linux_amd64.zip":    "b2c5881ea67e1c397cb26c6162d81829e058d5a993801bcb69df9982412d27e9",
+	}
+	binaries, err := CreateFromFileList(options, files, nil, nil, shasums)
+	if err != nil {
+		t.Fatalf("unexpected error, %s", err)
+	}
+	names := make([]string, 0)
+	for name := range binaries.ExtractVersions() {
+		names = append(names, name)
+	}
+	if len(names) != 2 || binaries[1].Namespace != "mollie" {
+		t.Errorf("expected providers in two namespaces, got %v", names)
+	}
+}
@@ -0,0 +1,72 @@
+package versions
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// providerPartExpression matches a valid namespace or type of a provider address: it consists
+// of lowercase letters, digits and dashes, and does not start or end with a dash or contain
+// consecutive dashes.
+var providerPartExpression = regexp.MustCompile(`^[a-z0-9]+(-[a-z0-9]+)*$`)
+
+func validateProviderPart(kind string, name string) error {
+	if name == "" {
+		return fmt.Errorf("the provider %s must not be empty", kind)
+	}
+	if !providerPartExpression.MatchString(name) {
+		return fmt.Errorf("the provider %s '%s' is invalid, it may only contain lowercase letters, digits and single dashes, and must start and end with a letter or digit", kind, name)
+	}
+	return nil
+}
+
+// ValidateNamespace checks that the namespace is valid in a provider address.
+func ValidateNamespace(namespace string) error {
+	return validateProviderPart("namespace", namespace)
+}
+
+// ValidateTypeName checks that the type name is valid in a provider address.
+func ValidateTypeName(typeName string) error {
+	if err := validateProviderPart("type", typeName); err != nil {
+		return err
+	}
+	if strings.HasPrefix(typeName, "terraform-") {
+		return fmt.Errorf("the provider type '%s' is invalid, it must not start with 'terraform-'", typeName)
+	}
+	return nil
+}
+
+// ValidateReleaseFiles checks that the namespaces and type names of all release files are
+// valid. With a prefix template, the type and version of each file must match its directory.
+func ValidateReleaseFiles(options *ReleaseOptions, files []string) error {
+	messages := make([]string, 0)
+	for _, filename := range files {
+		release := options.Scheme.ParseArchive(filename)
+		if release == nil {
+			release = options.Scheme.ParseShasums(filename)
+		}
+		if release == nil {
+			release = options.Scheme.ParseSignature(filename)
+		}
+		if release == nil {
+			continue
+		}
+		release.Namespace = options.namespaceOf(filename)
+		if err := ValidateNamespace(release.Namespace); err != nil {
+			messages = append(messages, fmt.Sprintf("%s: %s", filename, err))
+		}
+		if err := ValidateTypeName(release.TypeName); err != nil {
+			messages = append(messages, fmt.Sprintf("%s: %s", filename, err))
+		}
+		if options.PrefixTemplate != nil {
+			if err := options.PrefixTemplate.Check(filename, release); err != nil {
+				messages = append(messages, fmt.Sprintf("%s: %s", filename, err))
+			}
+		}
+	}
+	if len(messages) > 0 {
+		return fmt.Errorf("invalid release file names:\n  %s", strings.Join(messages, "\n  "))
+	}
+	return nil
+}
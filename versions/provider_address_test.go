@@ -0,0 +1,32 @@
+package versions
+
+import "testing"
+
+func TestValidateTypeName(t *testing.T) {
+	tests := []struct {
+		name  string
+		valid bool
+	}{
+		{"sentry", true},
+		{"google-beta", true},
+		{"k8s", true},
+		{"", false},
+		{"my_thing", false},
+		{"Sentry", false},
+		{"-sentry", false},
+		{"sentry-", false},
+		{"google--beta", false},
+		{"terraform-sentry", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateTypeName(tt.name)
+			if tt.valid && err != nil {
+				t.Errorf("expected %s to be valid, %s", tt.name, err)
+			}
+			if !tt.valid && err == nil {
+				t.Errorf("expected %s to be invalid", tt.name)
+			}
+		})
+	}
+}
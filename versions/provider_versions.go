@@ -1,7 +1,8 @@
 package versions
 
 import (
-	"log"
+	"fmt"
+	goversion "github.com/hashicorp/go-version"
 	"sort"
 	"strconv"
 	"strings"
@@ -17,27 +18,29 @@ type ProviderVersionList []ProviderVersion
 
 type SemVer []int
 
-func MakeSemVerFromString(semver string) SemVer {
+func MakeSemVerFromString(semver string) (SemVer, error) {
 	var result SemVer
 	parts := strings.Split(semver, ".")
 
 	for _, v := range parts {
 		value, err := strconv.Atoi(v)
 		if err != nil {
-			log.Fatalf("invalid semver '%s'", semver)
+			return nil, fmt.Errorf("invalid semver '%s'", semver)
 		}
 		result = append(result, value)
 	}
 
-	return result
+	return result, nil
 }
 
-func (v ProviderVersion) GetSemVer() SemVer {
-	result := MakeSemVerFromString(v.Version)
-	if len(result) != 3 {
-		log.Fatalf("invalid semantic version '%s'", v.Version)
+// GetSemVer returns the major, minor and patch version, without the pre-release or build
+// suffix.
+func (v ProviderVersion) GetSemVer() (SemVer, error) {
+	result, err := MakeSemVerFromString(strings.SplitN(strings.SplitN(v.Version, "+", 2)[0], "-", 2)[0])
+	if err != nil || len(result) != 3 {
+		return nil, fmt.Errorf("invalid semantic version '%s'", v.Version)
 	}
-	return result
+	return result, nil
 }
 
 func (v SemVer) Less(o SemVer) bool {
@@ -53,13 +56,27 @@ func (v SemVer) Less(o SemVer) bool {
 
 func (a ProviderVersionList) Len() int           { return len(a) }
 func (a ProviderVersionList) Swap(i, j int)      { a[i], a[j] = a[j], a[i] }
-func (a ProviderVersionList) Less(i, j int) bool { return a[i].GetSemVer().Less(a[j].GetSemVer()) }
+// Less orders the versions by their semantic version. Invalid versions, which are rejected
+// by Validate, are ordered as strings.
+func (a ProviderVersionList) Less(i, j int) bool {
+	this, err := goversion.NewVersion(a[i].Version)
+	other, otherErr := goversion.NewVersion(a[j].Version)
+	if err != nil || otherErr != nil {
+		return a[i].Version < a[j].Version
+	}
+	return this.LessThan(other)
+}
 
 type ProtocolList []string
 func (a ProtocolList) Len() int      { return len(a) }
 func (a ProtocolList) Swap(i, j int) { a[i], a[j] = a[j], a[i] }
 func (a ProtocolList) Less(i, j int) bool {
-	return MakeSemVerFromString(a[i]).Less(MakeSemVerFromString(a[j]))
+	this, err := MakeSemVerFromString(a[i])
+	other, otherErr := MakeSemVerFromString(a[j])
+	if err != nil || otherErr != nil {
+		return a[i] < a[j]
+	}
+	return this.Less(other)
 }
 
 func (l *ProviderVersions) Add(meta *BinaryMetaData) {
@@ -114,6 +131,28 @@ func (v *ProviderVersion) AddPlatform(platform Platform) {
 	sort.Sort(PlatformList(v.Platforms))
 }
 
+// MissingPlatforms returns the required platforms which are not available for the version.
+func (v *ProviderVersion) MissingPlatforms(required PlatformList) PlatformList {
+	result := make(PlatformList, 0)
+	for _, platform := range required {
+		if !PlatformList(v.Platforms).Contains(platform) {
+			result = append(result, platform)
+		}
+	}
+	return result
+}
+
+// RemovePlatform removes the platform from the version, and returns true if it was listed.
+func (v *ProviderVersion) RemovePlatform(platform Platform) bool {
+	for i := range v.Platforms {
+		if v.Platforms[i].Equals(&platform) {
+			v.Platforms = append(v.Platforms[:i], v.Platforms[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
 func (v *ProviderVersion) AddPlatforms(platforms []Platform) {
 	for _, platform := range platforms {
 		v.AddPlatform(platform)
@@ -160,6 +199,17 @@ func (p *ProviderVersions) AddOrUpdateProviderVersion(v ProviderVersion) {
 	existingVersion.AddPlatforms(v.Platforms)
 }
 
+// RemoveVersion removes the version from the list, and returns true if it was listed.
+func (p *ProviderVersions) RemoveVersion(version string) bool {
+	for i := range p.Versions {
+		if p.Versions[i].Version == version {
+			p.Versions = append(p.Versions[:i], p.Versions[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
 func (p *ProviderVersions) Merge(o ProviderVersions) {
 	if o.Versions == nil {
 		return
@@ -168,3 +218,32 @@ func (p *ProviderVersions) Merge(o ProviderVersions) {
 		p.AddOrUpdateProviderVersion(version)
 	}
 }
+
+// Resolve returns the newest version which matches the constraint, like ">= 1.0, < 2.0" or
+// "~> 1.2". If the platform is not nil, only versions available for the platform are considered.
+func (p *ProviderVersions) Resolve(constraint string, platform *Platform) (*ProviderVersion, error) {
+	constraints, err := goversion.NewConstraint(constraint)
+	if err != nil {
+		return nil, fmt.Errorf("invalid version constraint %s, %s", constraint, err)
+	}
+
+	var result *ProviderVersion
+	var newest *goversion.Version
+	for i, v := range p.Versions {
+		candidate, err := goversion.NewVersion(v.Version)
+		if err != nil || !constraints.Check(candidate) {
+			continue
+		}
+		if platform != nil && !PlatformList(v.Platforms).Contains(*platform) {
+			continue
+		}
+		if newest == nil || candidate.GreaterThan(newest) {
+			newest = candidate
+			result = &p.Versions[i]
+		}
+	}
+	if result == nil {
+		return nil, fmt.Errorf("no version matches %s", constraint)
+	}
+	return result, nil
+}
@@ -0,0 +1,119 @@
+package versions
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"github.com/xeipuuv/gojsonschema"
+	"path"
+	"regexp"
+	"sync"
+)
+
+// DocumentKind is the kind of a provider registry API document.
+type DocumentKind string
+
+const (
+	DiscoveryDocument DocumentKind = "discovery"
+	VersionsDocument  DocumentKind = "versions"
+	DownloadDocument  DocumentKind = "download"
+)
+
+//go:embed schemas/*.schema.json
+var schemaFiles embed.FS
+
+var (
+	schemas                 map[DocumentKind]*gojsonschema.Schema
+	schemasError            error
+	loadSchemasOnce         sync.Once
+	downloadDocumentPattern = regexp.MustCompile(`/download/[^/]+/[^/]+$`)
+)
+
+func loadSchemas() (map[DocumentKind]*gojsonschema.Schema, error) {
+	loadSchemasOnce.Do(func() {
+		schemas = make(map[DocumentKind]*gojsonschema.Schema)
+		for _, kind := range []DocumentKind{DiscoveryDocument, VersionsDocument, DownloadDocument} {
+			content, err := schemaFiles.ReadFile(fmt.Sprintf("schemas/%s.schema.json", kind))
+			if err != nil {
+				schemasError = err
+				return
+			}
+			schema, err := gojsonschema.NewSchema(gojsonschema.NewBytesLoader(content))
+			if err != nil {
+				schemasError = fmt.Errorf("invalid schema of %s document, %s", kind, err)
+				return
+			}
+			schemas[kind] = schema
+		}
+	})
+	return schemas, schemasError
+}
+
+// DocumentKindOf returns the kind of the document. The kind is determined by the name of
+// the document, or if the name is not conclusive, by its properties.
+func DocumentKindOf(name string, content []byte) (DocumentKind, error) {
+	switch {
+	case path.Base(name) == "terraform.json":
+		return DiscoveryDocument, nil
+	case path.Base(name) == "versions":
+		return VersionsDocument, nil
+	case downloadDocumentPattern.MatchString(name):
+		return DownloadDocument, nil
+	}
+
+	var properties map[string]json.RawMessage
+	if err := json.Unmarshal(content, &properties); err != nil {
+		return "", fmt.Errorf("%s is not a JSON object, %s", name, err)
+	}
+	if _, ok := properties["providers.v1"]; ok {
+		return DiscoveryDocument, nil
+	}
+	if _, ok := properties["versions"]; ok {
+		return VersionsDocument, nil
+	}
+	if _, ok := properties["download_url"]; ok {
+		return DownloadDocument, nil
+	}
+	return "", fmt.Errorf("%s is not a discovery, versions or download document", name)
+}
+
+// ValidateDocument checks the document against the JSON schema of its kind, and the versions
+// and download documents against the provider registry protocol.
+func ValidateDocument(kind DocumentKind, content []byte) error {
+	schemas, err := loadSchemas()
+	if err != nil {
+		return err
+	}
+	schema, ok := schemas[kind]
+	if !ok {
+		return fmt.Errorf("unknown document kind %s", kind)
+	}
+
+	result, err := schema.Validate(gojsonschema.NewBytesLoader(content))
+	if err != nil {
+		return fmt.Errorf("%s document is not valid JSON, %s", kind, err)
+	}
+	if !result.Valid() {
+		messages := make([]string, 0, len(result.Errors()))
+		for _, e := range result.Errors() {
+			messages = append(messages, e.String())
+		}
+		return validationError(fmt.Sprintf("%s document", kind), messages)
+	}
+
+	switch kind {
+	case VersionsDocument:
+		var document ProviderVersions
+		if err = json.Unmarshal(content, &document); err != nil {
+			return err
+		}
+		return document.Validate()
+	case DownloadDocument:
+		var document BinaryMetaData
+		if err = json.Unmarshal(content, &document); err != nil {
+			return err
+		}
+		return document.Validate()
+	}
+	return nil
+}
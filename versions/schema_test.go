@@ -0,0 +1,71 @@
+package versions
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestValidateDocument(t *testing.T) {
+	download := `{
+  "protocols": ["5.0"],
+  "os": "darwin",
+  "arch": "amd64",
+  "filename": "terraform-provider-sentry_0.6.0_darwin_amd64.zip",
+  "download_url": "https://registry.example.com/terraform-provider-sentry_0.6.0_darwin_amd64.zip",
+  "shasums_url": "https://registry.example.com/terraform-provider-sentry_0.6.0_SHA256SUMS",
+  "shasums_signature_url": "https://registry.example.com/terraform-provider-sentry_0.6.0_SHA256SUMS.sig",
+  "shasum": "%s",
+  "signing_keys": {"gpg_public_keys": [{"key_id": "B64689ABE6ED9C52", "ascii_armor": "-----BEGIN PGP PUBLIC KEY BLOCK-----", "source_url": null}]}
+}`
+	shasum := "a2c5881ea67e1c397cb26c6162d81829e058d5a993801bcb69df9982412d27e9"
+
+	tests := []struct {
+		name     string
+		document string
+		kind     DocumentKind
+		wantErr  bool
+	}{
+		{"discovery", `{"providers.v1": "/v1/providers/"}`, DiscoveryDocument, false},
+		{"discovery_without_providers", `{"modules.v1": "/v1/modules/"}`, DiscoveryDocument, true},
+		{"versions", `{"versions": [{"version": "0.6.0", "protocols": ["5.0"], "platforms": [{"os": "linux", "arch": "amd64"}]}]}`, VersionsDocument, false},
+		{"versions_without_protocols", `{"versions": [{"version": "0.6.0", "protocols": [], "platforms": [{"os": "linux", "arch": "amd64"}]}]}`, VersionsDocument, true},
+		{"versions_without_os", `{"versions": [{"version": "0.6.0", "protocols": ["5.0"], "platforms": [{"arch": "amd64"}]}]}`, VersionsDocument, true},
+		{"versions_prerelease", `{"versions": [{"version": "1.0.0-beta1+build.5", "protocols": ["5.0"], "platforms": [{"os": "linux", "arch": "amd64"}]}]}`, VersionsDocument, false},
+		{"versions_invalid_version", `{"versions": [{"version": "v0.6", "protocols": ["5.0"], "platforms": [{"os": "linux", "arch": "amd64"}]}]}`, VersionsDocument, true},
+		{"download", fmt.Sprintf(download, shasum), DownloadDocument, false},
+		{"download_short_shasum", fmt.Sprintf(download, shasum[:32]), DownloadDocument, true},
+		{"not_json", `{"versions": [`, VersionsDocument, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := ValidateDocument(tt.kind, []byte(tt.document)); (err != nil) != tt.wantErr {
+				t.Errorf("expected error %v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestDocumentKindOf(t *testing.T) {
+	tests := []struct {
+		name     string
+		document string
+		want     DocumentKind
+		wantErr  bool
+	}{
+		{".well-known/terraform.json", `{}`, DiscoveryDocument, false},
+		{"v1/providers/mollie/mollie/versions", `{}`, VersionsDocument, false},
+		{"https://registry.example.com/v1/providers/mollie/mollie/1.0.0/download/linux/amd64", `{}`, DownloadDocument, false},
+		{"download.json", `{"download_url": ""}`, DownloadDocument, false},
+		{"versions.json", `{"versions": []}`, VersionsDocument, false},
+		{"other.json", `{"modules.v1": ""}`, "", true},
+		{"other.json", `[]`, "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := DocumentKindOf(tt.name, []byte(tt.document))
+			if (err != nil) != tt.wantErr || got != tt.want {
+				t.Errorf("expected %s, error %v, got %s, %v", tt.want, tt.wantErr, got, err)
+			}
+		})
+	}
+}
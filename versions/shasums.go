@@ -0,0 +1,65 @@
+package versions
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"path"
+	"regexp"
+	"strings"
+)
+
+var (
+	shasumExpression    = regexp.MustCompile(`^[0-9a-fA-F]{64}$`)
+	bsdShasumExpression = regexp.MustCompile(`^SHA256 ?\((?P<file>.+)\) ?= ?(?P<shasum>[0-9a-fA-F]{64})$`)
+)
+
+// ParseShasums reads SHA-256 checksums into shasums, keyed by the base name of the file. It
+// accepts the GNU text and binary mode formats (`<hash>  file` and `<hash> *file`) and the BSD
+// format (`SHA256 (file) = <hash>`). Blank lines are ignored.
+func ParseShasums(r io.Reader, shasums map[string]string) error {
+	scanner := bufio.NewScanner(r)
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		filename, shasum, err := parseShasumLine(line)
+		if err != nil {
+			return fmt.Errorf("line %d, %s", lineNumber, err)
+		}
+		shasums[path.Base(filename)] = strings.ToLower(shasum)
+	}
+	return scanner.Err()
+}
+
+// ParseShasumsOf reads the SHA256SUMS of the release files in the directory into shasums,
+// keyed by the path of the file. Releases in different directories may contain files with the
+// same name, for example the same provider type in two namespaces.
+func ParseShasumsOf(r io.Reader, directory string, shasums map[string]string) error {
+	parsed := make(map[string]string)
+	if err := ParseShasums(r, parsed); err != nil {
+		return err
+	}
+	for filename, shasum := range parsed {
+		shasums[path.Join(directory, filename)] = shasum
+	}
+	return nil
+}
+
+func parseShasumLine(line string) (filename string, shasum string, err error) {
+	if matches := bsdShasumExpression.FindStringSubmatch(line); matches != nil {
+		return matches[1], matches[2], nil
+	}
+
+	fields := strings.SplitN(line, " ", 2)
+	if len(fields) != 2 || !shasumExpression.MatchString(fields[0]) {
+		return "", "", fmt.Errorf("expected '<sha256> <filename>', found '%s'", line)
+	}
+	filename = strings.TrimLeft(fields[1], " ")
+	filename = strings.TrimPrefix(filename, "*")
+	if filename == "" {
+		return "", "", fmt.Errorf("no filename found in '%s'", line)
+	}
+	return filename, fields[0], nil
+}
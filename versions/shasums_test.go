@@ -0,0 +1,58 @@
+package versions
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseShasums(t *testing.T) {
+	shasum := "a2c5881ea67e1c397cb26c6162d81829e058d5a993801bcb69df9982412d27e9"
+	tests := []struct {
+		name    string
+		content string
+		want    map[string]string
+		wantErr bool
+	}{
+		{"text_mode",
+			shasum + "  terraform-provider-sentry_0.6.0_darwin_amd64.zip\n",
+			map[string]string{"terraform-provider-sentry_0.6.0_darwin_amd64.zip": shasum}, false,
+		},
+		{"binary_mode",
+			shasum + " *terraform-provider-sentry_0.6.0_darwin_amd64.zip\n",
+			map[string]string{"terraform-provider-sentry_0.6.0_darwin_amd64.zip": shasum}, false,
+		},
+		{"bsd_format",
+			"SHA256 (terraform-provider-sentry_0.6.0_darwin_amd64.zip) = " + shasum + "\n",
+			map[string]string{"terraform-provider-sentry_0.6.0_darwin_amd64.zip": shasum}, false,
+		},
+		{"path_prefix",
+			shasum + "  ./dist/terraform-provider-sentry_0.6.0_darwin_amd64.zip\n",
+			map[string]string{"terraform-provider-sentry_0.6.0_darwin_amd64.zip": shasum}, false,
+		},
+		{"upper_case_and_blank_lines",
+			"\n" + strings.ToUpper(shasum) + "  terraform-provider-sentry_0.6.0_darwin_amd64.zip\r\n\n",
+			map[string]string{"terraform-provider-sentry_0.6.0_darwin_amd64.zip": shasum}, false,
+		},
+		{"invalid_shasum",
+			"a2c5881  terraform-provider-sentry_0.6.0_darwin_amd64.zip\n",
+			map[string]string{}, true,
+		},
+		{"missing_filename",
+			shasum + "\n",
+			map[string]string{}, true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			shasums := make(map[string]string)
+			err := ParseShasums(strings.NewReader(tt.content), shasums)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("expected error %v, got %v", tt.wantErr, err)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(shasums, tt.want) {
+				t.Errorf("expected %v, got %v", tt.want, shasums)
+			}
+		})
+	}
+}
@@ -41,8 +41,14 @@ func TestSemVer_Less(t *testing.T) {
 	for _, tt := range tests {
 
 		t.Run(tt.name, func(t *testing.T) {
-			this := MakeSemVerFromString(tt.this)
-			other := MakeSemVerFromString(tt.other)
+			this, err := MakeSemVerFromString(tt.this)
+			if err != nil {
+				t.Fatalf("%s", err)
+			}
+			other, err := MakeSemVerFromString(tt.other)
+			if err != nil {
+				t.Fatalf("%s", err)
+			}
 			result := this.Less(other)
 			op_result := other.Less(this)
 			if result != tt.want {
@@ -59,6 +65,27 @@ func TestSemVer_Less(t *testing.T) {
 	}
 }
 
+func TestProviderVersion_GetSemVer(t *testing.T) {
+	tests := []struct {
+		version string
+		want    SemVer
+		wantErr bool
+	}{
+		{"1.2.3", SemVer{1, 2, 3}, false},
+		{"1.2.3-beta1+build.5", SemVer{1, 2, 3}, false},
+		{"1.2", nil, true},
+		{"v1.2.3", nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.version, func(t *testing.T) {
+			got, err := ProviderVersion{Version: tt.version}.GetSemVer()
+			if (err != nil) != tt.wantErr || !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("expected %v, error %v, got %v, %v", tt.want, tt.wantErr, got, err)
+			}
+		})
+	}
+}
+
 func TestProviderVersions_Merge(t *testing.T) {
 	tests := []struct {
 		name   string
@@ -76,6 +103,11 @@ func TestProviderVersions_Merge(t *testing.T) {
 			`{"versions":[{"version":"0.5.9","protocols":["5.0"],"platforms":[{"os":"darwin","arch":"amd64"}]}]}`,
 			`{"versions":[{"version":"0.5.9","protocols":["5.0"],"platforms":[{"os":"darwin","arch":"amd64"}]}, {"version":"0.6.1","protocols":["5.0"],"platforms":[{"os":"darwin","arch":"amd64"}]}]}`,
 		},
+		{"add_prerelease_version",
+			`{"versions":[{"version":"1.0.0","protocols":["5.0"],"platforms":[{"os":"darwin","arch":"amd64"}]}]}`,
+			`{"versions":[{"version":"1.0.0-beta1","protocols":["5.0"],"platforms":[{"os":"darwin","arch":"amd64"}]}]}`,
+			`{"versions":[{"version":"1.0.0-beta1","protocols":["5.0"],"platforms":[{"os":"darwin","arch":"amd64"}]}, {"version":"1.0.0","protocols":["5.0"],"platforms":[{"os":"darwin","arch":"amd64"}]}]}`,
+		},
 		{"add_a_new_protocol",
 			`{"versions":[{"version":"0.6.1","protocols":["5.1"],"platforms":[{"os":"darwin","arch":"amd64"}]}]}`,
 			`{"versions":[{"version":"0.6.1","protocols":["5.0"],"platforms":[{"os":"darwin","arch":"amd64"}]}]}`,
@@ -129,3 +161,49 @@ func TestProviderVersions_Merge(t *testing.T) {
 		})
 	}
 }
+
+func TestProviderVersion_MissingPlatforms(t *testing.T) {
+	required, err := ParsePlatformList("darwin_amd64,darwin/arm64,windows_amd64")
+	if err != nil {
+		t.Fatalf("failed to parse platforms, %s", err)
+	}
+	version := ProviderVersion{Version: "0.6.1"}
+	version.AddPlatforms([]Platform{{Os: "darwin", Arch: "amd64"}, {Os: "linux", Arch: "amd64"}})
+
+	missing := version.MissingPlatforms(required)
+	want := PlatformList{{Os: "darwin", Arch: "arm64"}, {Os: "windows", Arch: "amd64"}}
+	if !reflect.DeepEqual(missing, want) {
+		t.Errorf("expected %v missing, got %v", want, missing)
+	}
+
+	if _, err = ParsePlatformList("darwn_amd64"); err == nil {
+		t.Errorf("expected unknown os darwn to be rejected")
+	}
+	if _, err = ParsePlatformList("linux_amd46"); err == nil {
+		t.Errorf("expected unknown arch amd46 to be rejected")
+	}
+}
+
+func TestProviderVersions_Remove(t *testing.T) {
+	var providerVersions ProviderVersions
+	providerVersions.AddProviderVersion(ProviderVersion{Version: "0.6.0", Platforms: []Platform{{Os: "linux", Arch: "amd64"}}})
+	providerVersions.AddProviderVersion(ProviderVersion{Version: "0.6.1", Platforms: []Platform{{Os: "darwin", Arch: "amd64"}, {Os: "linux", Arch: "amd64"}}})
+
+	version := providerVersions.FindVersion("0.6.1")
+	if !version.RemovePlatform(Platform{Os: "darwin", Arch: "amd64"}) {
+		t.Errorf("expected darwin_amd64 to be removed")
+	}
+	if version.RemovePlatform(Platform{Os: "darwin", Arch: "amd64"}) {
+		t.Errorf("expected darwin_amd64 to be removed only once")
+	}
+	if !reflect.DeepEqual(version.Platforms, []Platform{{Os: "linux", Arch: "amd64"}}) {
+		t.Errorf("unexpected platforms %v", version.Platforms)
+	}
+
+	if !providerVersions.RemoveVersion("0.6.0") || providerVersions.RemoveVersion("0.6.0") {
+		t.Errorf("expected 0.6.0 to be removed once")
+	}
+	if len(providerVersions.Versions) != 1 || providerVersions.Versions[0].Version != "0.6.1" {
+		t.Errorf("unexpected versions %v", providerVersions.Versions)
+	}
+}
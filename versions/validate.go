@@ -0,0 +1,64 @@
+package versions
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+var semVerExpression = regexp.MustCompile(`^[0-9]+\.[0-9]+\.[0-9]+(-[0-9A-Za-z.-]+)?(\+[0-9A-Za-z.-]+)?$`)
+
+func validationError(name string, messages []string) error {
+	if len(messages) == 0 {
+		return nil
+	}
+	return fmt.Errorf("%s is invalid: %s", name, strings.Join(messages, ", "))
+}
+
+// Validate checks that the download document contains all fields required by the
+// provider registry protocol.
+func (m *BinaryMetaData) Validate() error {
+	messages := make([]string, 0)
+	if len(m.Protocols) == 0 {
+		messages = append(messages, "no protocols")
+	}
+	if m.Os == "" || m.Arch == "" {
+		messages = append(messages, "no os or arch")
+	}
+	if m.Filename == "" {
+		messages = append(messages, "no filename")
+	}
+	if m.DownloadURL == "" || m.ShasumsURL == "" || m.ShasumsSignatureURL == "" {
+		messages = append(messages, "missing download_url, shasums_url or shasums_signature_url")
+	}
+	if !shasumExpression.MatchString(m.Shasum) {
+		messages = append(messages, fmt.Sprintf("shasum '%s' is not a SHA-256", m.Shasum))
+	}
+	if len(m.SigningKeys.GpgPublicKeys) == 0 {
+		messages = append(messages, "no signing keys")
+	}
+	for _, key := range m.SigningKeys.GpgPublicKeys {
+		if key.KeyID == "" || key.ASCIIArmor == "" {
+			messages = append(messages, "signing key without key_id or ascii_armor")
+		}
+	}
+	return validationError(fmt.Sprintf("download document of %s", m.Filename), messages)
+}
+
+// Validate checks that the versions document contains all fields required by the provider
+// registry protocol.
+func (p *ProviderVersions) Validate() error {
+	messages := make([]string, 0)
+	for _, v := range p.Versions {
+		if !semVerExpression.MatchString(v.Version) {
+			messages = append(messages, fmt.Sprintf("version '%s' is not a semantic version", v.Version))
+		}
+		if len(v.Protocols) == 0 {
+			messages = append(messages, fmt.Sprintf("version %s has no protocols", v.Version))
+		}
+		if len(v.Platforms) == 0 {
+			messages = append(messages, fmt.Sprintf("version %s has no platforms", v.Version))
+		}
+	}
+	return validationError("versions document", messages)
+}
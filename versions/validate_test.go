@@ -0,0 +1,62 @@
+package versions
+
+import "testing"
+
+func TestBinaryMetaData_Validate(t *testing.T) {
+	valid := func() BinaryMetaData {
+		m := BinaryMetaData{
+			Protocols:           []string{"5.0"},
+			Os:                  "darwin",
+			Arch:                "amd64",
+			Filename:            "terraform-provider-sentry_0.6.0_darwin_amd64.zip",
+			DownloadURL:         "https://registry.example.com/terraform-provider-sentry_0.6.0_darwin_amd64.zip",
+			ShasumsURL:          "https://registry.example.com/terraform-provider-sentry_0.6.0_SHA256SUMS",
+			ShasumsSignatureURL: "https://registry.example.com/terraform-provider-sentry_0.6.0_SHA256SUMS.sig",
+			Shasum:              "a2c5881ea67e1c397cb26c6162d81829e058d5a993801bcb69df9982412d27e9",
+		}
+		m.SigningKeys.GpgPublicKeys = []GpgSigningKey{{KeyID: "B64689ABE6ED9C52", ASCIIArmor: "-----BEGIN PGP PUBLIC KEY BLOCK-----"}}
+		return m
+	}
+
+	tests := []struct {
+		name    string
+		modify  func(m *BinaryMetaData)
+		wantErr bool
+	}{
+		{"valid", func(m *BinaryMetaData) {}, false},
+		{"no_protocols", func(m *BinaryMetaData) { m.Protocols = nil }, true},
+		{"no_arch", func(m *BinaryMetaData) { m.Arch = "" }, true},
+		{"short_shasum", func(m *BinaryMetaData) { m.Shasum = "a2c5881e" }, true},
+		{"no_signing_keys", func(m *BinaryMetaData) { m.SigningKeys.GpgPublicKeys = nil }, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := valid()
+			tt.modify(&m)
+			if err := m.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("expected error %v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestProviderVersions_Validate(t *testing.T) {
+	tests := []struct {
+		version string
+		wantErr bool
+	}{
+		{"1.0.0", false},
+		{"1.0.0-beta1", false},
+		{"1.0.0-rc.1+build.5", false},
+		{"v1.0.0", true},
+		{"1.0", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.version, func(t *testing.T) {
+			p := ProviderVersions{Versions: []ProviderVersion{{Version: tt.version, Protocols: []string{"5.0"}, Platforms: []Platform{{Os: "linux", Arch: "amd64"}}}}}
+			if err := p.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("expected error %v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}